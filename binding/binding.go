@@ -0,0 +1,261 @@
+package binding
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strings"
+)
+
+// 解析multipart表单时默认使用的内存上限
+const defaultMultipartMemory = 32 << 20 // 32 MB
+
+// 结构体tag名
+const (
+	// 表单和查询参数
+	FormTag = "form"
+	// 路径参数
+	ParamTag = "param"
+	// 请求头
+	HeaderTag = "header"
+)
+
+var (
+	ErrEmptyBody = errors.New("binding: empty request body")
+)
+
+// 将请求中的数据绑定到结构体 不做校验
+type Binding interface {
+	Name() string
+	Bind(r *http.Request, v interface{}) error
+}
+
+var (
+	JSON          Binding = jsonBinding{}
+	XML           Binding = xmlBinding{}
+	Form          Binding = formBinding{}
+	FormMultipart Binding = multipartBinding{}
+	Query         Binding = queryBinding{}
+	Header        Binding = headerBinding{}
+)
+
+// 根据请求方法和Content-Type选择绑定方式 GET和HEAD请求绑定查询参数
+func Default(method string, contentType string) Binding {
+	if method == http.MethodGet || method == http.MethodHead {
+		return Form
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return JSON
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return XML
+	case mediaType == "multipart/form-data":
+		return FormMultipart
+	default:
+		return Form
+	}
+}
+
+// 按Content-Type绑定请求 绑定成功后使用Validator校验
+func Bind(r *http.Request, v interface{}) error {
+	return validate(v, Default(r.Method, r.Header.Get("Content-Type")).Bind(r, v))
+}
+
+type jsonBinding struct{}
+
+func (jsonBinding) Name() string {
+	return "json"
+}
+
+// 类型不匹配时返回Errors 与表单一致 Field为结构体字段路径 Name为json中的字段路径
+func (jsonBinding) Bind(r *http.Request, v interface{}) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return ErrEmptyBody
+	}
+	err := json.NewDecoder(r.Body).Decode(v)
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return Errors{&FieldError{
+			Field: jsonFieldPath(reflect.TypeOf(v), typeErr.Field),
+			Name:  typeErr.Field,
+			Value: typeErr.Value,
+			Err:   fmt.Errorf("cannot unmarshal %s into %s", typeErr.Value, typeErr.Type),
+		}}
+	}
+	return err
+}
+
+// 将json中的字段路径转换为结构体的字段路径 无法对应时返回原路径
+func jsonFieldPath(t reflect.Type, keys string) string {
+	if keys == "" {
+		return keys
+	}
+	path := ""
+	for _, key := range strings.Split(keys, ".") {
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return keys
+		}
+		sf, ok := jsonField(t, key)
+		if !ok {
+			return keys
+		}
+		path = joinPath(path, sf.Name)
+		t = sf.Type
+	}
+	return path
+}
+
+// 按json中的名字查找字段 没有tag的匿名结构体字段与外层在同一层
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			if f, ok := jsonField(ft, key); ok {
+				return f, true
+			}
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if strings.EqualFold(name, key) {
+			return sf, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+type xmlBinding struct{}
+
+func (xmlBinding) Name() string {
+	return "xml"
+}
+
+func (xmlBinding) Bind(r *http.Request, v interface{}) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return ErrEmptyBody
+	}
+	return xml.NewDecoder(r.Body).Decode(v)
+}
+
+// 绑定查询参数和urlencoded表单
+type formBinding struct{}
+
+func (formBinding) Name() string {
+	return "form"
+}
+
+func (formBinding) Bind(r *http.Request, v interface{}) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	return MapValues(v, r.Form, FormTag)
+}
+
+// 绑定multipart表单 *multipart.FileHeader和[]*multipart.FileHeader字段绑定上传的文件
+// 请求已经解析过时使用已有的结果
+type multipartBinding struct{}
+
+func (multipartBinding) Name() string {
+	return "multipart/form-data"
+}
+
+func (multipartBinding) Bind(r *http.Request, v interface{}) error {
+	if err := r.ParseMultipartForm(defaultMultipartMemory); err != nil {
+		return err
+	}
+	m := newMapper(valuesSource(r.Form), FormTag)
+	m.files = r.MultipartForm.File
+	return m.bind(v)
+}
+
+type queryBinding struct{}
+
+func (queryBinding) Name() string {
+	return "query"
+}
+
+func (queryBinding) Bind(r *http.Request, v interface{}) error {
+	return MapValues(v, r.URL.Query(), FormTag)
+}
+
+// 绑定请求头 tag中的名字不区分大小写
+type headerBinding struct{}
+
+func (headerBinding) Name() string {
+	return "header"
+}
+
+func (headerBinding) Bind(r *http.Request, v interface{}) error {
+	return newMapper(headerSource(r.Header), HeaderTag).bind(v)
+}
+
+// 按tag将values绑定到结构体 v必须是非nil的结构体指针
+func MapValues(v interface{}, values map[string][]string, tag string) error {
+	return newMapper(valuesSource(values), tag).bind(v)
+}
+
+type valuesSource map[string][]string
+
+func (s valuesSource) get(key string) ([]string, bool) {
+	values, ok := s[key]
+	return values, ok
+}
+
+func (s valuesSource) hasPrefix(prefix string) bool {
+	for key := range s {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// 请求头的名字不区分大小写
+type headerSource map[string][]string
+
+func (s headerSource) get(key string) ([]string, bool) {
+	values, ok := s[textproto.CanonicalMIMEHeaderKey(key)]
+	return values, ok
+}
+
+func (s headerSource) hasPrefix(prefix string) bool {
+	for key := range s {
+		if len(key) >= len(prefix) && strings.EqualFold(key[:len(prefix)], prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// 绑定成功后校验
+func validate(v interface{}, err error) error {
+	if err != nil {
+		return err
+	}
+	return Validate(v)
+}
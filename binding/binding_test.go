@@ -0,0 +1,312 @@
+package binding
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type address struct {
+	City string `form:"city"`
+	Zip  int    `form:"zip"`
+}
+
+type paging struct {
+	Page int `form:"page"`
+	Size int `form:"size"`
+}
+
+type profile struct {
+	paging
+	Name     string        `form:"name"`
+	Age      uint8         `form:"age"`
+	Active   bool          `form:"active"`
+	Score    float64       `form:"score"`
+	Tags     []string      `form:"tag"`
+	IDs      []int         `form:"id"`
+	Birthday time.Time     `form:"birthday" time_format:"2006-01-02"`
+	Created  time.Time     `form:"created" time_format:"unix"`
+	Timeout  time.Duration `form:"timeout"`
+	Nick     *string       `form:"nick"`
+	Home     address       `form:"home"`
+	Work     *address      `form:"work"`
+	Ignored  string        `form:"-"`
+	Plain    string
+	secret   string
+}
+
+func TestMapValues(t *testing.T) {
+	values := url.Values{
+		"page":      {"2"},
+		"name":      {"glu"},
+		"age":       {"18"},
+		"active":    {"true"},
+		"score":     {"9.5"},
+		"tag":       {"a", "b"},
+		"id":        {"1", "2", "3"},
+		"birthday":  {"2020-01-02"},
+		"created":   {"1600000000"},
+		"timeout":   {"1m30s"},
+		"home.city": {"beijing"},
+		"home.zip":  {"100000"},
+		"Ignored":   {"x"},
+		"Plain":     {"plain"},
+		"secret":    {"x"},
+	}
+	var p profile
+	if err := MapValues(&p, values, FormTag); err != nil {
+		t.Fatal(err)
+	}
+	if p.Page != 2 || p.Name != "glu" || p.Age != 18 || !p.Active || p.Score != 9.5 {
+		t.Fatalf("unexpected scalars: %+v", p)
+	}
+	if strings.Join(p.Tags, ",") != "a,b" || len(p.IDs) != 3 || p.IDs[2] != 3 {
+		t.Fatalf("unexpected slices: %v %v", p.Tags, p.IDs)
+	}
+	if !p.Birthday.Equal(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)) || p.Created.Unix() != 1600000000 {
+		t.Fatalf("unexpected times: %v %v", p.Birthday, p.Created)
+	}
+	if p.Timeout != 90*time.Second {
+		t.Fatalf("unexpected duration: %v", p.Timeout)
+	}
+	if p.Home.City != "beijing" || p.Home.Zip != 100000 {
+		t.Fatalf("unexpected nested struct: %+v", p.Home)
+	}
+	if p.Nick != nil || p.Work != nil {
+		t.Fatal("pointers without values should stay nil")
+	}
+	if p.Ignored != "" || p.Plain != "plain" || p.secret != "" {
+		t.Fatalf("unexpected tag handling: %q %q %q", p.Ignored, p.Plain, p.secret)
+	}
+
+	values = url.Values{"nick": {"g"}, "work.city": {"shanghai"}}
+	p = profile{}
+	if err := MapValues(&p, values, FormTag); err != nil {
+		t.Fatal(err)
+	}
+	if p.Nick == nil || *p.Nick != "g" || p.Work == nil || p.Work.City != "shanghai" {
+		t.Fatalf("pointers should be allocated: %v %v", p.Nick, p.Work)
+	}
+}
+
+type node struct {
+	Name string `form:"name"`
+	Next *node  `form:"next"`
+}
+
+func TestMapValuesSelfReference(t *testing.T) {
+	var n node
+	if err := MapValues(&n, url.Values{"name": {"a"}}, FormTag); err != nil {
+		t.Fatal(err)
+	}
+	if n.Name != "a" || n.Next != nil {
+		t.Fatalf("unexpected result: %+v", n)
+	}
+	values := url.Values{"name": {"a"}, "next.next.name": {"c"}}
+	if err := MapValues(&n, values, FormTag); err != nil {
+		t.Fatal(err)
+	}
+	if n.Next == nil || n.Next.Name != "" || n.Next.Next == nil || n.Next.Next.Name != "c" || n.Next.Next.Next != nil {
+		t.Fatalf("unexpected result: %+v", n.Next)
+	}
+	// 超过最大层数的字段不再绑定
+	values = url.Values{strings.Repeat("next.", maxDepth+10) + "name": {"deep"}}
+	n = node{}
+	if err := MapValues(&n, values, FormTag); err != nil {
+		t.Fatal(err)
+	}
+	depth := 0
+	for p := n.Next; p != nil; p = p.Next {
+		depth++
+	}
+	if depth > maxDepth {
+		t.Fatalf("nesting should stop at %d levels, got %d", maxDepth, depth)
+	}
+}
+
+func TestMapValuesErrors(t *testing.T) {
+	values := url.Values{
+		"age":      {"300"},
+		"active":   {"maybe"},
+		"id":       {"1", "x"},
+		"birthday": {"2020/01/02"},
+		"home.zip": {"abc"},
+	}
+	var p profile
+	err := MapValues(&p, values, FormTag)
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected Errors, got %v", err)
+	}
+	fields := make([]string, 0, len(errs))
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	if got := strings.Join(fields, ","); got != "Age,Active,IDs[1],Birthday,Home.Zip" {
+		t.Fatalf("unexpected fields: %s", got)
+	}
+	if errs[4].Name != "home.zip" || errs[4].Value != "abc" {
+		t.Fatalf("unexpected error detail: %+v", errs[4])
+	}
+	if p.IDs[0] != 1 {
+		t.Fatal("valid values should still be bound")
+	}
+
+	if err := MapValues(p, values, FormTag); err == nil {
+		t.Fatal("non-pointer target should fail")
+	}
+}
+
+func TestDefault(t *testing.T) {
+	cases := []struct {
+		method      string
+		contentType string
+		want        Binding
+	}{
+		{http.MethodGet, "application/json", Form},
+		{http.MethodPost, "application/json; charset=utf-8", JSON},
+		{http.MethodPost, "application/problem+json", JSON},
+		{http.MethodPut, "application/xml", XML},
+		{http.MethodPost, "text/xml", XML},
+		{http.MethodPost, "multipart/form-data; boundary=x", FormMultipart},
+		{http.MethodPost, "application/x-www-form-urlencoded", Form},
+		{http.MethodPost, "", Form},
+	}
+	for _, c := range cases {
+		if got := Default(c.method, c.contentType); got != c.want {
+			t.Errorf("%s %s: got %s, want %s", c.method, c.contentType, got.Name(), c.want.Name())
+		}
+	}
+}
+
+type user struct {
+	Name string `json:"name" xml:"name" form:"name"`
+	Age  int    `json:"age" xml:"age" form:"age"`
+}
+
+func TestBindBody(t *testing.T) {
+	cases := []struct {
+		contentType string
+		body        string
+	}{
+		{"application/json", `{"name":"glu","age":3}`},
+		{"application/xml", `<user><name>glu</name><age>3</age></user>`},
+		{"application/x-www-form-urlencoded", "name=glu&age=3"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(c.body))
+		r.Header.Set("Content-Type", c.contentType)
+		var u user
+		if err := Bind(r, &u); err != nil {
+			t.Fatalf("%s: %v", c.contentType, err)
+		}
+		if u.Name != "glu" || u.Age != 3 {
+			t.Fatalf("%s: unexpected result %+v", c.contentType, u)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"glu","age":"old"}`))
+	r.Header.Set("Content-Type", "application/json")
+	var u user
+	var errs Errors
+	if err := Bind(r, &u); !errors.As(err, &errs) || errs[0].Field != "Age" || errs[0].Name != "age" {
+		t.Fatalf("expected field error for age, got %v", err)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("Content-Type", "application/json")
+	if err := Bind(r, &u); err != ErrEmptyBody {
+		t.Fatalf("expected ErrEmptyBody, got %v", err)
+	}
+}
+
+func TestBindMultipart(t *testing.T) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	_ = w.WriteField("name", "glu")
+	for _, name := range []string{"a.txt", "b.txt"} {
+		f, _ := w.CreateFormFile("files", name)
+		_, _ = f.Write([]byte(name))
+	}
+	f, _ := w.CreateFormFile("avatar", "avatar.png")
+	_, _ = f.Write([]byte("png"))
+	_ = w.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/?age=3", &body)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+	var form struct {
+		Name   string                  `form:"name"`
+		Age    int                     `form:"age"`
+		Avatar *multipart.FileHeader   `form:"avatar"`
+		Files  []*multipart.FileHeader `form:"files"`
+	}
+	if err := Bind(r, &form); err != nil {
+		t.Fatal(err)
+	}
+	if form.Name != "glu" || form.Age != 3 || form.Avatar == nil || form.Avatar.Filename != "avatar.png" || len(form.Files) != 2 {
+		t.Fatalf("unexpected result: %+v", form)
+	}
+}
+
+func TestBindQueryAndHeader(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/?page=3&size=20", strings.NewReader("page=9"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-Request-Id", "abc")
+	r.Header.Add("Accept-Language", "zh")
+	r.Header.Add("Accept-Language", "en")
+
+	var p paging
+	if err := Query.Bind(r, &p); err != nil || p.Page != 3 || p.Size != 20 {
+		t.Fatalf("unexpected query result: %+v %v", p, err)
+	}
+	var h struct {
+		RequestID string   `header:"x-request-id"`
+		Languages []string `header:"Accept-Language"`
+	}
+	if err := Header.Bind(r, &h); err != nil || h.RequestID != "abc" || len(h.Languages) != 2 {
+		t.Fatalf("unexpected header result: %+v %v", h, err)
+	}
+}
+
+func TestJSONFieldPath(t *testing.T) {
+	type location struct {
+		City string `json:"city"`
+	}
+	type Base struct {
+		ID int `json:"id"`
+	}
+	type order struct {
+		Base
+		Address  *location  `json:"address"`
+		Contacts []location `json:"contacts"`
+		Note     string
+		Meta     map[string]int `json:"meta"`
+	}
+	tests := map[string]string{
+		"id":            "ID",
+		"address.city":  "Address.City",
+		"contacts.city": "Contacts.City",
+		"Note":          "Note",
+		"meta.size":     "meta.size",
+		"missing":       "missing",
+	}
+	for keys, expected := range tests {
+		if path := jsonFieldPath(reflect.TypeOf(&order{}), keys); path != expected {
+			t.Fatalf("jsonFieldPath(%q) = %q, expected %q", keys, path, expected)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"address":{"city":1}}`))
+	var o order
+	var errs Errors
+	if err := JSON.Bind(r, &o); !errors.As(err, &errs) || errs[0].Field != "Address.City" || errs[0].Name != "address.city" {
+		t.Fatalf("expected struct field path with json name, got %v", err)
+	}
+}
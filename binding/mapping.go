@@ -0,0 +1,331 @@
+package binding
+
+import (
+	"encoding"
+	"fmt"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	durationType    = reflect.TypeOf(time.Duration(0))
+	fileType        = reflect.TypeOf((*multipart.FileHeader)(nil))
+	filesType       = reflect.TypeOf([]*multipart.FileHeader(nil))
+	unmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// 字段转换错误
+type FieldError struct {
+	// 字段路径 如 Address.City Tags[1]
+	Field string
+	// 请求中的参数名
+	Name  string
+	Value string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("field %s: cannot bind %s=%q: %v", e.Field, e.Name, e.Value, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// 所有字段的转换错误
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// 嵌套结构体的最大层数 超过的字段不再绑定
+const maxDepth = 32
+
+// 绑定时的取值来源
+type source interface {
+	// 按参数名取值
+	get(key string) ([]string, bool)
+	// 是否存在以prefix开头的参数
+	hasPrefix(prefix string) bool
+}
+
+// 按tag从source中取值绑定到结构体字段
+// 没有tag时使用字段名 tag为-时跳过 嵌套结构体的参数名为 父字段名.子字段名
+// 没有tag的匿名结构体字段与外层使用相同的前缀
+type mapper struct {
+	source source
+	files  map[string][]*multipart.FileHeader
+	tag    string
+	errs   Errors
+	depth  int
+}
+
+func newMapper(source source, tag string) *mapper {
+	return &mapper{source: source, tag: tag}
+}
+
+func (m *mapper) bind(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("binding: target must be a non-nil pointer to struct, got %T", v)
+	}
+	m.mapStruct(rv.Elem(), "", "")
+	if len(m.errs) > 0 {
+		return m.errs
+	}
+	return nil
+}
+
+// 绑定结构体的所有字段 返回是否有字段被设置
+func (m *mapper) mapStruct(rv reflect.Value, prefix string, path string) bool {
+	rt := rv.Type()
+	set := false
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		// 未导出的匿名结构体仍然绑定其导出的字段
+		if sf.PkgPath != "" && !(sf.Anonymous && sf.Type.Kind() == reflect.Struct && !isScalar(sf.Type)) {
+			continue
+		}
+		tag := sf.Tag.Get(m.tag)
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		fv := rv.Field(i)
+		if sf.Anonymous && name == "" && fv.Kind() == reflect.Struct {
+			set = m.mapStruct(fv, prefix, path) || set
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		set = m.mapField(fv, sf, prefix+name, joinPath(path, sf.Name)) || set
+	}
+	return set
+}
+
+func (m *mapper) mapField(fv reflect.Value, sf reflect.StructField, key string, path string) bool {
+	switch {
+	case fv.Type() == fileType:
+		if files := m.files[key]; len(files) > 0 {
+			fv.Set(reflect.ValueOf(files[0]))
+			return true
+		}
+		return false
+	case fv.Type() == filesType:
+		if files := m.files[key]; len(files) > 0 {
+			fv.Set(reflect.ValueOf(files))
+			return true
+		}
+		return false
+	case fv.Kind() == reflect.Ptr:
+		// 结构体指针只在有 key. 开头的参数时分配 自引用的类型不会无限递归
+		if elem := fv.Type().Elem(); elem.Kind() == reflect.Struct && !isScalar(elem) && !m.hasPrefix(key+".") {
+			return false
+		}
+		// 只有设置了值时才分配
+		nv := reflect.New(fv.Type().Elem())
+		if m.mapField(nv.Elem(), sf, key, path) {
+			fv.Set(nv)
+			return true
+		}
+		return false
+	case fv.Kind() == reflect.Struct && !isScalar(fv.Type()):
+		if m.depth >= maxDepth {
+			return false
+		}
+		m.depth++
+		defer func() { m.depth-- }()
+		return m.mapStruct(fv, key+".", path)
+	}
+
+	values, ok := m.source.get(key)
+	if !ok || len(values) == 0 {
+		return false
+	}
+	switch fv.Kind() {
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.Uint8 {
+			fv.SetBytes([]byte(values[0]))
+			return true
+		}
+		slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+		for i, value := range values {
+			m.setValue(slice.Index(i), sf, key, fmt.Sprintf("%s[%d]", path, i), value)
+		}
+		fv.Set(slice)
+	case reflect.Array:
+		if len(values) != fv.Len() {
+			m.fail(path, key, strings.Join(values, ","), fmt.Errorf("expected %d values, got %d", fv.Len(), len(values)))
+			return false
+		}
+		for i, value := range values {
+			m.setValue(fv.Index(i), sf, key, fmt.Sprintf("%s[%d]", path, i), value)
+		}
+	default:
+		m.setValue(fv, sf, key, path, values[0])
+	}
+	return true
+}
+
+// 转换单个值 失败时记录错误
+func (m *mapper) setValue(v reflect.Value, sf reflect.StructField, key string, path string, value string) {
+	if err := setScalar(v, sf, value); err != nil {
+		m.fail(path, key, value, err)
+	}
+}
+
+// 参数或上传的文件中是否有以prefix开头的名字
+func (m *mapper) hasPrefix(prefix string) bool {
+	if m.source.hasPrefix(prefix) {
+		return true
+	}
+	for name := range m.files {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *mapper) fail(path string, key string, value string, err error) {
+	m.errs = append(m.errs, &FieldError{Field: path, Name: key, Value: value, Err: err})
+}
+
+// 时间和实现了encoding.TextUnmarshaler的结构体按单个值处理
+func isScalar(t reflect.Type) bool {
+	return t == timeType || reflect.PtrTo(t).Implements(unmarshalerType)
+}
+
+// 将字符串转换为字段的类型 数字和布尔值为空时使用零值
+func setScalar(v reflect.Value, sf reflect.StructField, value string) error {
+	if v.Kind() == reflect.Ptr {
+		nv := reflect.New(v.Type().Elem())
+		if err := setScalar(nv.Elem(), sf, value); err != nil {
+			return err
+		}
+		v.Set(nv)
+		return nil
+	}
+	switch v.Type() {
+	case timeType:
+		return setTime(v, sf, value)
+	case durationType:
+		if value == "" {
+			v.SetInt(0)
+			return nil
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(unmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		if value == "" {
+			v.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value == "" {
+			v.SetInt(0)
+			return nil
+		}
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value == "" {
+			v.SetUint(0)
+			return nil
+		}
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if value == "" {
+			v.SetFloat(0)
+			return nil
+		}
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		v.Set(reflect.ValueOf(value))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// 时间格式由time_format指定 默认RFC3339 unix表示秒级时间戳
+// time_location指定没有时区时使用的时区 默认UTC
+func setTime(v reflect.Value, sf reflect.StructField, value string) error {
+	if value == "" {
+		v.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+	format := sf.Tag.Get("time_format")
+	if format == "unix" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(time.Unix(n, 0)))
+		return nil
+	}
+	if format == "" {
+		format = time.RFC3339
+	}
+	loc := time.UTC
+	if name := sf.Tag.Get("time_location"); name != "" {
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return err
+		}
+		loc = l
+	}
+	t, err := time.ParseInLocation(format, value, loc)
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(t))
+	return nil
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
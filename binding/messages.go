@@ -0,0 +1,88 @@
+package binding
+
+import (
+	"strings"
+	"sync"
+)
+
+// ValidationError.Error使用的语言
+var DefaultLanguage = "en"
+
+// 校验失败的提示信息 按语言和规则名查找
+// 规则名.string 和 规则名.items 分别用于字符串和集合 空规则名为找不到规则时的默认信息
+// {field} {rule} {param} 会被替换为字段路径、规则名和参数
+var messages = struct {
+	sync.RWMutex
+	m map[string]map[string]string
+}{m: map[string]map[string]string{
+	"en": {
+		"":           "{field} failed on the '{rule}' rule",
+		"required":   "{field} is required",
+		"min":        "{field} must be {param} or greater",
+		"min.string": "{field} must be at least {param} characters long",
+		"min.items":  "{field} must contain at least {param} items",
+		"max":        "{field} must be {param} or less",
+		"max.string": "{field} must be at most {param} characters long",
+		"max.items":  "{field} must contain at most {param} items",
+		"len":        "{field} must be equal to {param}",
+		"len.string": "{field} must be exactly {param} characters long",
+		"len.items":  "{field} must contain exactly {param} items",
+		"oneof":      "{field} must be one of [{param}]",
+		"regex":      "{field} has an invalid format",
+		"email":      "{field} must be a valid email address",
+	},
+	"zh": {
+		"":           "{field}未通过{rule}校验",
+		"required":   "{field}不能为空",
+		"min":        "{field}不能小于{param}",
+		"min.string": "{field}长度不能少于{param}个字符",
+		"min.items":  "{field}至少包含{param}项",
+		"max":        "{field}不能大于{param}",
+		"max.string": "{field}长度不能超过{param}个字符",
+		"max.items":  "{field}最多包含{param}项",
+		"len":        "{field}必须等于{param}",
+		"len.string": "{field}长度必须是{param}个字符",
+		"len.items":  "{field}必须包含{param}项",
+		"oneof":      "{field}必须是[{param}]中的一个",
+		"regex":      "{field}格式不正确",
+		"email":      "{field}必须是有效的邮箱地址",
+	},
+}}
+
+// 注册或覆盖某种语言的提示信息 语言名不区分大小写
+func RegisterMessages(lang string, msgs map[string]string) {
+	lang = strings.ToLower(lang)
+	messages.Lock()
+	defer messages.Unlock()
+	m := messages.m[lang]
+	if m == nil {
+		m = make(map[string]string, len(msgs))
+		messages.m[lang] = m
+	}
+	for rule, msg := range msgs {
+		m[rule] = msg
+	}
+}
+
+// 按语言生成提示信息 如zh-CN依次查找zh-cn zh和DefaultLanguage
+func (e *ValidationError) Translate(lang string) string {
+	langs := []string{strings.ToLower(lang)}
+	if i := strings.IndexAny(langs[0], "-_"); i > 0 {
+		langs = append(langs, langs[0][:i])
+	}
+	langs = append(langs, strings.ToLower(DefaultLanguage))
+	keys := []string{e.Rule, ""}
+	if e.kind != "" {
+		keys = []string{e.Rule + "." + e.kind, e.Rule, ""}
+	}
+	messages.RLock()
+	defer messages.RUnlock()
+	for _, key := range keys {
+		for _, l := range langs {
+			if msg, ok := messages.m[l][key]; ok {
+				return strings.NewReplacer("{field}", e.Field, "{rule}", e.Rule, "{param}", e.Param).Replace(msg)
+			}
+		}
+	}
+	return e.Field + " failed on the '" + e.Rule + "' rule"
+}
@@ -0,0 +1,464 @@
+package binding
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// 校验规则使用的tag名
+const ValidateTag = "validate"
+
+// 绑定完成后校验结构体
+type StructValidator interface {
+	ValidateStruct(v interface{}) error
+}
+
+// Bind和Context.Bind绑定成功后使用的校验器 为nil时不校验
+var Validator StructValidator = defaultValidator{}
+
+// 使用Validator校验v
+func Validate(v interface{}) error {
+	if Validator == nil {
+		return nil
+	}
+	return Validator.ValidateStruct(v)
+}
+
+// 自定义校验规则 v为字段的值(指针已解引用) param为规则的参数 返回是否通过
+type ValidationFunc func(v reflect.Value, param string) bool
+
+// 不能被覆盖的规则
+var reservedRules = map[string]bool{"required": true, "omitempty": true, "dive": true}
+
+var validations = struct {
+	sync.RWMutex
+	m map[string]ValidationFunc
+}{m: map[string]ValidationFunc{
+	"min":   validateMin,
+	"max":   validateMax,
+	"len":   validateLen,
+	"oneof": validateOneOf,
+	"regex": validateRegex,
+	"email": validateEmail,
+}}
+
+// 注册自定义校验规则 同名规则会被覆盖 应在绑定请求之前注册
+func RegisterValidation(name string, fn ValidationFunc) {
+	if name == "" || fn == nil {
+		panic("binding: validation name and func must not be empty")
+	}
+	if reservedRules[name] {
+		panic(fmt.Sprintf("binding: validation '%s' is reserved", name))
+	}
+	validations.Lock()
+	validations.m[name] = fn
+	validations.Unlock()
+}
+
+// 校验失败的字段
+type ValidationError struct {
+	// 字段路径 如 Address.City Tags[1]
+	Field string
+	// 未通过的规则和参数
+	Rule  string
+	Param string
+	Value interface{}
+	// 值的类别 string items 或空 用于选择提示信息
+	kind string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Translate(DefaultLanguage)
+}
+
+// 所有校验失败的字段 每个字段只记录第一个未通过的规则
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// 按语言获取每个字段的提示信息
+func (e ValidationErrors) Translate(lang string) map[string]string {
+	messages := make(map[string]string, len(e))
+	for _, err := range e {
+		messages[err.Field] = err.Translate(lang)
+	}
+	return messages
+}
+
+// 规则校验失败时返回ValidationErrors 规则写错时返回普通错误
+// 支持的规则
+//
+//	required    值不能为空 指针不能为nil
+//	omitempty   值为空时跳过后续规则
+//	min max len 字符串按字符数 切片和map按元素个数 数字按大小比较
+//	oneof       值为空格分隔的参数之一 如 oneof=red green
+//	regex       匹配正则表达式 必须是最后一个规则 逗号之后的内容都属于表达式
+//	email       邮件地址
+//	dive        之后的规则作用于切片、数组和map的每个元素
+//
+// 嵌套的结构体和元素为结构体的集合会递归校验 nil指针只校验required
+type defaultValidator struct{}
+
+func (defaultValidator) ValidateStruct(v interface{}) error {
+	s := &validation{}
+	s.nested(reflect.ValueOf(v), "")
+	if s.err != nil {
+		return s.err
+	}
+	if len(s.errs) > 0 {
+		return s.errs
+	}
+	return nil
+}
+
+type rule struct {
+	name  string
+	param string
+	fn    ValidationFunc
+}
+
+type fieldRules struct {
+	index int
+	name  string
+	// 没有tag的匿名结构体 字段路径与外层相同
+	embedded bool
+	rules    []rule
+}
+
+// 解析过的结构体规则
+var structCache sync.Map
+
+// 解析过的正则表达式
+var regexCache sync.Map
+
+type validation struct {
+	errs ValidationErrors
+	err  error
+}
+
+// 按规则校验一个值 全部通过后递归校验其中的结构体
+func (s *validation) field(v reflect.Value, rules []rule, path string) {
+	for i, r := range rules {
+		switch r.name {
+		case "omitempty":
+			if isEmpty(v) {
+				return
+			}
+			continue
+		case "required":
+			if isEmpty(v) {
+				s.fail(v, r, path)
+				return
+			}
+			continue
+		case "dive":
+			s.dive(indirect(v), rules[i+1:], path)
+			return
+		}
+		if isNil(v) {
+			return
+		}
+		if !r.fn(indirect(v), r.param) {
+			s.fail(v, r, path)
+			return
+		}
+	}
+	s.nested(v, path)
+}
+
+func (s *validation) dive(v reflect.Value, rules []rule, path string) {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			s.field(v.Index(i), rules, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+		for _, key := range keys {
+			s.field(v.MapIndex(key), rules, fmt.Sprintf("%s[%v]", path, key))
+		}
+	}
+}
+
+// 递归校验结构体字段和集合中的结构体
+func (s *validation) nested(v reflect.Value, path string) {
+	v = indirect(v)
+	if !v.IsValid() || !hasStruct(v.Type()) {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		fields, err := structRules(v.Type())
+		if err != nil {
+			s.err = err
+			return
+		}
+		for _, f := range fields {
+			if s.err != nil {
+				return
+			}
+			if f.embedded {
+				s.nested(v.Field(f.index), path)
+				continue
+			}
+			s.field(v.Field(f.index), f.rules, joinPath(path, f.name))
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		s.dive(v, nil, path)
+	}
+}
+
+func (s *validation) fail(v reflect.Value, r rule, path string) {
+	e := &ValidationError{Field: path, Rule: r.name, Param: r.param}
+	if v.IsValid() && v.CanInterface() {
+		e.Value = v.Interface()
+	}
+	switch indirect(v).Kind() {
+	case reflect.String:
+		e.kind = "string"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		e.kind = "items"
+	}
+	s.errs = append(s.errs, e)
+}
+
+// 解析结构体的校验规则 结果按类型缓存
+func structRules(t reflect.Type) ([]fieldRules, error) {
+	if cached, ok := structCache.Load(t); ok {
+		return cached.([]fieldRules), nil
+	}
+	var fields []fieldRules
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		ft := sf.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		anonymousStruct := sf.Anonymous && ft.Kind() == reflect.Struct && !isScalar(ft)
+		if sf.PkgPath != "" && !anonymousStruct {
+			continue
+		}
+		tag := sf.Tag.Get(ValidateTag)
+		if tag == "-" {
+			continue
+		}
+		if anonymousStruct && tag == "" {
+			fields = append(fields, fieldRules{index: i, embedded: true})
+			continue
+		}
+		rules, err := parseRules(tag, sf.Type)
+		if err != nil {
+			return nil, fmt.Errorf("binding: field %s.%s: %v", t.Name(), sf.Name, err)
+		}
+		if len(rules) == 0 && !hasStruct(sf.Type) {
+			continue
+		}
+		fields = append(fields, fieldRules{index: i, name: sf.Name, rules: rules})
+	}
+	structCache.Store(t, fields)
+	return fields, nil
+}
+
+func parseRules(tag string, t reflect.Type) ([]rule, error) {
+	var rules []rule
+	for tag != "" {
+		item := tag
+		if strings.HasPrefix(tag, "regex=") {
+			tag = ""
+		} else if i := strings.IndexByte(tag, ','); i >= 0 {
+			item, tag = tag[:i], tag[i+1:]
+		} else {
+			tag = ""
+		}
+		name, param := item, ""
+		if i := strings.IndexByte(item, '='); i >= 0 {
+			name, param = item[:i], item[i+1:]
+		}
+		r := rule{name: name, param: param}
+		switch name {
+		case "":
+			continue
+		case "required", "omitempty":
+		case "dive":
+			for t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			if t.Kind() != reflect.Slice && t.Kind() != reflect.Array && t.Kind() != reflect.Map {
+				return nil, fmt.Errorf("dive on non-collection type %s", t)
+			}
+			rest, err := parseRules(tag, t.Elem())
+			if err != nil {
+				return nil, err
+			}
+			return append(append(rules, r), rest...), nil
+		default:
+			validations.RLock()
+			r.fn = validations.m[name]
+			validations.RUnlock()
+			if r.fn == nil {
+				return nil, fmt.Errorf("unknown validation rule '%s'", name)
+			}
+			if err := checkParam(name, param); err != nil {
+				return nil, err
+			}
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// 检查内置规则的参数
+func checkParam(name string, param string) error {
+	switch name {
+	case "min", "max", "len":
+		if _, err := strconv.ParseFloat(param, 64); err != nil {
+			return fmt.Errorf("rule '%s' needs a numeric param, got '%s'", name, param)
+		}
+	case "oneof":
+		if strings.TrimSpace(param) == "" {
+			return fmt.Errorf("rule 'oneof' needs at least one value")
+		}
+	case "regex":
+		re, err := regexp.Compile(param)
+		if err != nil {
+			return err
+		}
+		regexCache.Store(param, re)
+	}
+	return nil
+}
+
+// 类型中是否包含需要递归校验的结构体
+func hasStruct(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		return !isScalar(t)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return hasStruct(t.Elem())
+	}
+	return false
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func isNil(v reflect.Value) bool {
+	return !indirect(v).IsValid()
+}
+
+// 字符串、切片和map长度为0 指针为nil 其余类型为零值时为空
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.String, reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// 字符串的字符数 集合的元素个数 或数字的值
+func measure(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func compare(v reflect.Value, param string, ok func(n float64, p float64) bool) bool {
+	n, valid := measure(v)
+	p, err := strconv.ParseFloat(param, 64)
+	return valid && err == nil && ok(n, p)
+}
+
+func validateMin(v reflect.Value, param string) bool {
+	return compare(v, param, func(n float64, p float64) bool { return n >= p })
+}
+
+func validateMax(v reflect.Value, param string) bool {
+	return compare(v, param, func(n float64, p float64) bool { return n <= p })
+}
+
+func validateLen(v reflect.Value, param string) bool {
+	return compare(v, param, func(n float64, p float64) bool { return n == p })
+}
+
+func validateOneOf(v reflect.Value, param string) bool {
+	var value string
+	switch v.Kind() {
+	case reflect.String:
+		value = v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value = strconv.FormatUint(v.Uint(), 10)
+	default:
+		return false
+	}
+	for _, option := range strings.Fields(param) {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
+
+func validateRegex(v reflect.Value, param string) bool {
+	if v.Kind() != reflect.String {
+		return false
+	}
+	cached, ok := regexCache.Load(param)
+	if !ok {
+		re, err := regexp.Compile(param)
+		if err != nil {
+			return false
+		}
+		cached, _ = regexCache.LoadOrStore(param, re)
+	}
+	return cached.(*regexp.Regexp).MatchString(v.String())
+}
+
+func validateEmail(v reflect.Value, _ string) bool {
+	if v.Kind() != reflect.String {
+		return false
+	}
+	addr, err := mail.ParseAddress(v.String())
+	return err == nil && addr.Address == v.String()
+}
@@ -0,0 +1,196 @@
+package binding
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type item struct {
+	SKU   string `validate:"required,regex=^[A-Z]{2}-[0-9]{1,3}$"`
+	Count int    `validate:"min=1,max=99"`
+}
+
+type order struct {
+	Base
+	Name    string            `validate:"required,min=2,max=5"`
+	Email   string            `validate:"omitempty,email"`
+	Color   string            `validate:"oneof=red green"`
+	Tags    []string          `validate:"max=2,dive,len=3"`
+	Items   []item            `validate:"required"`
+	Extra   map[string]int    `validate:"dive,max=10"`
+	Note    *string           `validate:"min=1"`
+	Coupon  *string           `validate:"required"`
+	Labels  map[string]string `validate:"-"`
+	Skipped string
+}
+
+type Base struct {
+	ID int `validate:"min=1"`
+}
+
+func fields(err error) string {
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		return ""
+	}
+	names := make([]string, 0, len(errs))
+	for _, e := range errs {
+		names = append(names, e.Field+":"+e.Rule)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestValidate(t *testing.T) {
+	coupon := "c"
+	valid := order{
+		Base:   Base{ID: 1},
+		Name:   "glu",
+		Color:  "red",
+		Tags:   []string{"abc"},
+		Items:  []item{{SKU: "AB-1", Count: 1}},
+		Extra:  map[string]int{"a": 10},
+		Coupon: &coupon,
+	}
+	if err := Validate(&valid); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	empty := ""
+	invalid := order{
+		Name:  "g",
+		Email: "not-an-email",
+		Color: "blue",
+		Tags:  []string{"abc", "toolong"},
+		Items: []item{{SKU: "AB-1", Count: 1}, {SKU: "ab-1", Count: 100}},
+		Extra: map[string]int{"b": 11, "a": 12},
+		Note:  &empty,
+	}
+	want := "ID:min,Name:min,Email:email,Color:oneof,Tags[1]:len,Items[1].SKU:regex,Items[1].Count:max,Extra[a]:max,Extra[b]:max,Note:min,Coupon:required"
+	if got := fields(Validate(invalid)); got != want {
+		t.Fatalf("unexpected errors:\n got %s\nwant %s", got, want)
+	}
+
+	// 结构体以外的值不做校验
+	if err := Validate(map[string]int{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate((*order)(nil)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateRuleErrors(t *testing.T) {
+	cases := []interface{}{
+		&struct {
+			A string `validate:"unknown"`
+		}{},
+		&struct {
+			A string `validate:"min=x"`
+		}{},
+		&struct {
+			A string `validate:"dive,required"`
+		}{},
+		&struct {
+			A string `validate:"regex=("`
+		}{},
+	}
+	for _, c := range cases {
+		err := Validate(c)
+		var errs ValidationErrors
+		if err == nil || errors.As(err, &errs) {
+			t.Fatalf("%T: expected a rule error, got %v", c, err)
+		}
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	RegisterValidation("even", func(v reflect.Value, _ string) bool {
+		return v.Kind() == reflect.Int && v.Int()%2 == 0
+	})
+	s := struct {
+		N int `validate:"even"`
+	}{N: 3}
+	if got := fields(Validate(&s)); got != "N:even" {
+		t.Fatalf("unexpected errors: %s", got)
+	}
+	s.N = 4
+	if err := Validate(&s); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("registering a reserved rule should panic")
+		}
+	}()
+	RegisterValidation("required", func(reflect.Value, string) bool { return true })
+}
+
+func TestTranslate(t *testing.T) {
+	s := struct {
+		Name string   `validate:"min=3"`
+		Tags []string `validate:"min=1"`
+		Age  int      `validate:"max=10"`
+		Code string   `validate:"even"`
+	}{Name: "g", Tags: []string{}, Age: 11}
+	RegisterValidation("even", func(reflect.Value, string) bool { return false })
+
+	var errs ValidationErrors
+	if !errors.As(Validate(&s), &errs) || len(errs) != 4 {
+		t.Fatalf("expected 4 errors, got %v", errs)
+	}
+	en := errs.Translate("en-US")
+	if en["Name"] != "Name must be at least 3 characters long" ||
+		en["Tags"] != "Tags must contain at least 1 items" ||
+		en["Age"] != "Age must be 10 or less" ||
+		en["Code"] != "Code failed on the 'even' rule" {
+		t.Fatalf("unexpected messages: %v", en)
+	}
+	if zh := errs.Translate("zh-CN"); zh["Name"] != "Name长度不能少于3个字符" {
+		t.Fatalf("unexpected messages: %v", zh)
+	}
+	if errs[0].Error() != en["Name"] {
+		t.Fatalf("Error should use the default language: %s", errs[0].Error())
+	}
+
+	RegisterMessages("fr", map[string]string{"even": "{field} doit être pair"})
+	fr := errs.Translate("fr")
+	if fr["Code"] != "Code doit être pair" || fr["Age"] != en["Age"] {
+		t.Fatalf("unexpected messages: %v", fr)
+	}
+}
+
+func TestBindValidates(t *testing.T) {
+	var form struct {
+		Name string `form:"name" validate:"required"`
+		Age  int    `form:"age" validate:"min=18"`
+	}
+	r := httptest.NewRequest(http.MethodGet, "/?age=3", nil)
+	if got := fields(Bind(r, &form)); got != "Name:required,Age:min" {
+		t.Fatalf("unexpected errors: %s", got)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":"glu","Age":20}`))
+	r.Header.Set("Content-Type", "application/json")
+	if err := Bind(r, &form); err != nil {
+		t.Fatal(err)
+	}
+
+	// 单独的Binding不做校验 以便从多个来源绑定同一个结构体
+	r = httptest.NewRequest(http.MethodGet, "/?age=3", nil)
+	if err := Query.Bind(r, &form); err != nil {
+		t.Fatal(err)
+	}
+
+	// 关闭校验
+	Validator = nil
+	defer func() { Validator = defaultValidator{} }()
+	r = httptest.NewRequest(http.MethodGet, "/?age=3", nil)
+	if err := Bind(r, &form); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -9,3 +9,9 @@ type Instance struct {
 	Ephemeral   bool    `json:"ephemeral"`
 	Healthy     bool    `json:"healthy"`
 }
+
+// 服务及其实例列表
+type ServiceInfo struct {
+	Name  string     `json:"name"`
+	Hosts []Instance `json:"hosts"`
+}
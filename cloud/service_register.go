@@ -2,6 +2,7 @@ package cloud
 
 import (
 	"errors"
+	jsoniter "github.com/json-iterator/go"
 	"github.com/sirupsen/logrus"
 	"github.com/yyxing/glu/util"
 	"io/ioutil"
@@ -44,7 +45,7 @@ func (c *NamingClient) RegisterInstance(instance Instance) (bool, error) {
 	params["weight"] = strconv.FormatFloat(instance.Weight, 'f', -1, 64)
 	params["healthy"] = strconv.FormatBool(instance.Healthy)
 	params["ephemeral"] = strconv.FormatBool(instance.Ephemeral)
-	_, err := c.reqApi(http.MethodPost, ServicePath, params)
+	_, err := c.reqApi(http.MethodPost, ServicePath, params, false)
 	if err != nil {
 		return false, err
 	}
@@ -60,6 +61,32 @@ func (c *NamingClient) RegisterInstance(instance Instance) (bool, error) {
 	return true, nil
 }
 
+// 查询服务下的实例 healthyOnly为true时只返回健康的实例
+func (c *NamingClient) SelectInstances(serviceName string, healthyOnly bool) ([]Instance, error) {
+	params := make(map[string]string)
+	params["namespaceId"] = c.namespaceId
+	params["serviceName"] = serviceName
+	params["healthyOnly"] = strconv.FormatBool(healthyOnly)
+	result, err := c.reqApi(http.MethodGet, ServicePath+"/list", params, true)
+	if err != nil {
+		return nil, err
+	}
+	var service ServiceInfo
+	if err := jsoniter.UnmarshalFromString(result, &service); err != nil {
+		return nil, err
+	}
+	if !healthyOnly {
+		return service.Hosts, nil
+	}
+	instances := make([]Instance, 0, len(service.Hosts))
+	for _, instance := range service.Hosts {
+		if instance.Healthy {
+			instances = append(instances, instance)
+		}
+	}
+	return instances, nil
+}
+
 func (c *NamingClient) sendHeartBeat(info BeatInfo) {
 	api := ServiceBasePath + "/instance/beat"
 	params := make(map[string]string)
@@ -69,24 +96,31 @@ func (c *NamingClient) sendHeartBeat(info BeatInfo) {
 	heartBeat := time.NewTicker(5 * time.Second)
 	go func() {
 		for range heartBeat.C {
-			_, err := c.reqApi(http.MethodPut, api, params)
+			_, err := c.reqApi(http.MethodPut, api, params, false)
 			if err != nil {
 				logrus.Info(err)
 			}
 		}
 	}()
 }
-func (c *NamingClient) reqApi(method, path string, params map[string]string) (result string, err error) {
+
+// checkStatus为true时服务端返回错误状态码也视为失败 会重试其他服务端
+func (c *NamingClient) reqApi(method, path string, params map[string]string, checkStatus bool) (result string, err error) {
 	if c.servers == nil || len(c.servers) <= 0 {
 		return "", errors.New("server list is empty")
 	}
 	if len(c.servers) == 1 {
 		for i := 0; i < RetryRequestTimes; i++ {
 			resp, err := c.request(method, getAddress(c.servers[0])+path, params)
+			if checkStatus {
+				err = checkResponse(resp, err)
+			}
 			result := getResponseInfo(resp)
 			if err == nil {
-				logrus.Info(result)
-				return "", nil
+				if !checkStatus {
+					logrus.Info(result)
+				}
+				return result, nil
 			}
 			logrus.Printf("api<%s>,method:<%s>, params:<%s>, call domain error:<%+v> , result:<%s>", path, method,
 				util.ToJsonString(params), err, result)
@@ -96,9 +130,12 @@ func (c *NamingClient) reqApi(method, path string, params map[string]string) (re
 		index := rand.Intn(len(c.servers))
 		for i := 1; i <= len(c.servers); i++ {
 			resp, err := c.request(method, getAddress(c.servers[index])+path, params)
+			if checkStatus {
+				err = checkResponse(resp, err)
+			}
 			result := getResponseInfo(resp)
 			if err == nil {
-				return "", nil
+				return result, nil
 			}
 			logrus.Printf("api<%s>,method:<%s>, params:<%s>, call domain error:<%+v> , result:<%s>", path, method,
 				util.ToJsonString(params), err, result)
@@ -107,6 +144,21 @@ func (c *NamingClient) reqApi(method, path string, params map[string]string) (re
 		return "", errors.New("retry " + strconv.Itoa(RetryRequestTimes) + " times request failed!")
 	}
 }
+
+// 请求失败或服务端返回错误状态码时返回错误
+func checkResponse(response *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	if response == nil {
+		return errors.New("no response")
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		return errors.New("unexpected status " + response.Status)
+	}
+	return nil
+}
+
 func getResponseInfo(response *http.Response) string {
 	if response == nil {
 		return ""
@@ -148,6 +200,18 @@ func (c *NamingClient) request(method, path string, params map[string]string) (r
 }
 
 func (c *NamingClient) get(path string, header http.Header, params map[string]string) (response *http.Response, err error) {
+	request, reqErr := http.NewRequest(http.MethodGet, path+"?"+util.GetUrlFormedMap(params), nil)
+	if reqErr != nil {
+		err = reqErr
+		return
+	}
+	request.Header = header
+	resp, errDo := c.client.Do(request)
+	if errDo != nil {
+		err = errDo
+	} else {
+		response = resp
+	}
 	return
 }
 
@@ -0,0 +1,67 @@
+package context
+
+import (
+	stdcontext "context"
+	"net/http"
+)
+
+// 请求上下文中保存Context的key
+type contextKey struct{}
+
+// 将Context放入请求的上下文 标准库handler可以通过FromRequest取回
+func (c *Context) withRequest() *http.Request {
+	if v, _ := c.Request.Context().Value(contextKey{}).(*Context); v == c {
+		return c.Request
+	}
+	return c.Request.WithContext(stdcontext.WithValue(c.Request.Context(), contextKey{}, c))
+}
+
+// 从WrapH/WrapF/WrapMiddleware传入的请求中获取Context
+func FromRequest(r *http.Request) (*Context, bool) {
+	c, ok := r.Context().Value(contextKey{}).(*Context)
+	return c, ok
+}
+
+// 从WrapH/WrapF/WrapMiddleware传入的请求中获取路径参数
+func ParamsFromRequest(r *http.Request) Params {
+	if c, ok := FromRequest(r); ok {
+		return c.Params
+	}
+	return nil
+}
+
+// 将http.Handler转换为Handler
+func WrapH(h http.Handler) Handler {
+	return func(c *Context) {
+		h.ServeHTTP(c.Writer, c.withRequest())
+	}
+}
+
+// 将http.HandlerFunc转换为Handler
+func WrapF(f http.HandlerFunc) Handler {
+	return WrapH(f)
+}
+
+// 将标准库中间件转换为Handler 中间件调用next时继续执行后续的handler
+// 中间件替换的Writer和Request在后续handler中生效 中间件返回后恢复
+// 中间件没有调用next时终止处理链
+func WrapMiddleware(middleware func(http.Handler) http.Handler) Handler {
+	return func(c *Context) {
+		writer, request := c.Writer, c.Request
+		called := false
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			c.Writer, c.Request = NewResponseWriter(w), r
+			c.Next()
+			// 中间件替换了Writer时 在中间件返回前写出延迟的状态码
+			if c.Writer != writer {
+				c.Writer.WriteHeaderNow()
+			}
+		})
+		middleware(next).ServeHTTP(c.Writer, c.withRequest())
+		c.Writer, c.Request = writer, request
+		if !called {
+			c.Abort()
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package context
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWrapH(t *testing.T) {
+	ctx := newTestContext(http.MethodGet, "/users/42", "")
+	ctx.Params = append(ctx.Params, Param{Key: "id", Value: "42"})
+	ctx.SetHandlers(WrapF(func(w http.ResponseWriter, r *http.Request) {
+		c, ok := FromRequest(r)
+		if !ok || c != ctx {
+			t.Fatal("context should be reachable from the request")
+		}
+		_, _ = w.Write([]byte("user " + ParamsFromRequest(r).ByName("id")))
+	}))
+	ctx.Next()
+	if body := ctx.Writer.Unwrap().(*httptest.ResponseRecorder).Body.String(); body != "user 42" {
+		t.Fatalf("unexpected body: %q", body)
+	}
+	if ParamsFromRequest(httptest.NewRequest(http.MethodGet, "/", nil)) != nil {
+		t.Fatal("plain request should have no params")
+	}
+}
+
+// 标准库中间件 记录调用顺序并替换Writer
+type headerWriter struct {
+	http.ResponseWriter
+}
+
+func (w headerWriter) Write(b []byte) (int, error) {
+	w.Header().Set("X-Wrapped", "1")
+	return w.ResponseWriter.Write(b)
+}
+
+func TestWrapMiddleware(t *testing.T) {
+	var steps []string
+	middleware := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			steps = append(steps, "before:"+ParamsFromRequest(r).ByName("id"))
+			next.ServeHTTP(headerWriter{w}, r)
+			steps = append(steps, "after")
+		})
+	}
+	ctx := newTestContext(http.MethodGet, "/users/7", "")
+	recorder := ctx.Writer
+	ctx.Params = append(ctx.Params, Param{Key: "id", Value: "7"})
+	ctx.SetHandlers(WrapMiddleware(middleware), func(c *Context) {
+		steps = append(steps, "handler")
+		_, _ = c.WriteString("ok")
+	})
+	ctx.Next()
+	if got := strings.Join(steps, ","); got != "before:7,handler,after" {
+		t.Fatalf("got %s", got)
+	}
+	if recorder.Header().Get("X-Wrapped") != "1" {
+		t.Fatal("handler should write through the middleware's writer")
+	}
+	if ctx.Writer != recorder {
+		t.Fatal("writer should be restored after the middleware returns")
+	}
+
+	// 中间件不调用next时终止处理链
+	steps = nil
+	deny := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+	}
+	ctx = newTestContext(http.MethodGet, "/", "")
+	ctx.SetHandlers(WrapMiddleware(deny), func(c *Context) {
+		steps = append(steps, "handler")
+	})
+	ctx.Next()
+	if len(steps) != 0 || ctx.Writer.Status() != http.StatusForbidden {
+		t.Fatalf("chain should stop: %v", steps)
+	}
+}
@@ -0,0 +1,58 @@
+package context
+
+import (
+	"github.com/yyxing/glu/binding"
+	"net/http"
+)
+
+// 根据请求方法和Content-Type绑定请求到结构体 支持JSON、XML、urlencoded和multipart表单
+// 表单字段使用form tag 转换失败时返回binding.Errors 绑定后按validate tag校验 校验失败时返回binding.ValidationErrors
+func (c *Context) Bind(v interface{}) error {
+	if c.Request == nil {
+		return ErrResponseDetached
+	}
+	b := binding.Default(c.Request.Method, c.Request.Header.Get(ContentTypeHeaderKey))
+	if b == binding.FormMultipart {
+		// 按Context的内存上限解析 绑定时复用解析结果
+		if err := c.Request.ParseMultipartForm(c.MaxMultipartMemory); err != nil && err != http.ErrNotMultipart {
+			return err
+		}
+	}
+	if err := c.BindWith(v, b); err != nil {
+		return err
+	}
+	return c.Validate(v)
+}
+
+// 使用指定的方式绑定请求 不做校验
+// 从多个来源绑定同一个结构体时 全部绑定完成后调用Validate
+func (c *Context) BindWith(v interface{}, b binding.Binding) error {
+	if c.Request == nil {
+		return ErrResponseDetached
+	}
+	return b.Bind(c.Request, v)
+}
+
+// 绑定查询参数 使用form tag 不做校验
+func (c *Context) BindQuery(v interface{}) error {
+	return c.BindWith(v, binding.Query)
+}
+
+// 绑定请求头 使用header tag 不做校验
+func (c *Context) BindHeader(v interface{}) error {
+	return c.BindWith(v, binding.Header)
+}
+
+// 绑定路径参数 使用param tag 不做校验
+func (c *Context) BindParams(v interface{}) error {
+	values := make(map[string][]string, len(c.Params))
+	for _, p := range c.Params {
+		values[p.Key] = append(values[p.Key], p.Value)
+	}
+	return binding.MapValues(v, values, binding.ParamTag)
+}
+
+// 按validate tag校验结构体 校验失败时返回binding.ValidationErrors
+func (c *Context) Validate(v interface{}) error {
+	return binding.Validate(v)
+}
@@ -0,0 +1,72 @@
+package context
+
+import (
+	"errors"
+	"github.com/yyxing/glu/binding"
+	"net/http"
+	"testing"
+)
+
+func TestBind(t *testing.T) {
+	ctx := newTestContext(http.MethodPost, "/users/42?verbose=true", `{"name":"glu"}`)
+	ctx.Request.Header.Set(ContentTypeHeaderKey, "application/json")
+	ctx.Request.Header.Set("X-Token", "secret")
+	ctx.Params = append(ctx.Params, Param{Key: "id", Value: "42"})
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := ctx.Bind(&body); err != nil || body.Name != "glu" {
+		t.Fatalf("unexpected body: %+v %v", body, err)
+	}
+	var req struct {
+		ID      int    `param:"id"`
+		Verbose bool   `form:"verbose"`
+		Token   string `header:"x-token"`
+	}
+	if err := ctx.BindParams(&req); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.BindQuery(&req); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.BindHeader(&req); err != nil {
+		t.Fatal(err)
+	}
+	if req.ID != 42 || !req.Verbose || req.Token != "secret" {
+		t.Fatalf("unexpected result: %+v", req)
+	}
+}
+
+func TestBindValidates(t *testing.T) {
+	ctx := newTestContext(http.MethodGet, "/users/0?verbose=true", "")
+	ctx.Request.Header.Set("X-Token", "secret")
+	ctx.Params = append(ctx.Params, Param{Key: "id", Value: "0"})
+	var req struct {
+		ID      int    `param:"id" validate:"min=1"`
+		Verbose bool   `form:"verbose" validate:"required"`
+		Token   string `header:"x-token" validate:"required"`
+	}
+	// 分多次绑定时每一步都不校验
+	if err := ctx.BindParams(&req); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.BindQuery(&req); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.BindHeader(&req); err != nil {
+		t.Fatal(err)
+	}
+	var errs binding.ValidationErrors
+	if err := ctx.Validate(&req); !errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != "ID" {
+		t.Fatalf("expected validation error for ID, got %v", err)
+	}
+
+	// Bind绑定后立即校验
+	var form struct {
+		Name string `form:"name" validate:"required"`
+	}
+	if err := ctx.Bind(&form); !errors.As(err, &errs) || errs[0].Field != "Name" {
+		t.Fatalf("expected validation error for Name, got %v", err)
+	}
+}
@@ -10,17 +10,45 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 )
 
 const defaultMultipartMemory = 32 << 20 // 32 MB
 
 var (
 	json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+	ErrResponseDetached = errors.New("context is detached from the response")
 )
 
 type Handler func(*Context)
 type Handlers []Handler
 
+// 路径参数
+type Param struct {
+	Key   string
+	Value string
+}
+
+// 按匹配顺序排列的路径参数 Context复用时保留底层数组
+type Params []Param
+
+// 获取参数值
+func (ps Params) Get(key string) (string, bool) {
+	for i := range ps {
+		if ps[i].Key == key {
+			return ps[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// 获取参数值 不存在时返回空字符串
+func (ps Params) ByName(key string) string {
+	value, _ := ps.Get(key)
+	return value
+}
+
 const (
 	// ContentTypeHeaderKey is the header key of "Content-Type".
 	ContentTypeHeaderKey = "Content-Type"
@@ -57,15 +85,23 @@ const (
 )
 
 type Context struct {
-	Writer              http.ResponseWriter
+	// 包装后的响应 可以获取状态码和写入的字节数
+	Writer              ResponseWriter
 	Request             *http.Request
 	Path                string
 	Method              string
-	Params              map[string]string
+	Params              Params
 	handlers            Handlers
 	currentHandlerIndex int
 	formCache           map[string][]string
 	MaxMultipartMemory  int64
+	// 释放时执行的钩子
+	releaseHooks []func()
+	released     bool
+	// 请求结束后仍会被使用 不放回池中
+	retained bool
+	// Writer默认指向writer 复用Context时不再分配内存
+	writer responseWriter
 }
 
 func (c *Context) Next() {
@@ -97,6 +133,9 @@ func NewContext() *Context {
 
 // 获取header中的值
 func (c *Context) Query(key string) string {
+	if c.Request == nil {
+		return ""
+	}
 	return c.Request.URL.Query().Get(key)
 }
 
@@ -118,13 +157,28 @@ func (c *Context) Write(body []byte) (int, error) {
 	return c.Writer.Write(body)
 }
 func (c *Context) Param(key string) string {
-	return c.Params[key]
+	return c.Params.ByName(key)
+}
+
+// 获取int类型的路径参数
+func (c *Context) ParamInt(key string) (int, error) {
+	return strconv.Atoi(c.Param(key))
+}
+
+// 获取int64类型的路径参数
+func (c *Context) ParamInt64(key string) (int64, error) {
+	return strconv.ParseInt(c.Param(key), 10, 64)
+}
+
+// 获取UUID类型的路径参数
+func (c *Context) ParamUUID(key string) (UUID, error) {
+	return ParseUUID(c.Param(key))
 }
 func (c *Context) WriteString(str string) (int, error) {
 	return c.Write([]byte(str))
 }
 
-// 设置状态码
+// 设置状态码 响应头在第一次写入body或请求处理结束时写出 之前仍然可以修改响应头
 func (c *Context) StatusCode(statusCode int) {
 	c.Writer.WriteHeader(statusCode)
 }
@@ -141,11 +195,95 @@ func (c *Context) Header(key string, value string) {
 func (c *Context) Abort() {
 	c.currentHandlerIndex = len(c.handlers)
 }
+
+// 设置底层的http.ResponseWriter 状态码和写入字节数重新计算
+func (c *Context) SetWriter(w http.ResponseWriter) {
+	c.writer.reset(w)
+	c.Writer = &c.writer
+}
+
+// 重置Context 需要先调用SetWriter并设置Request 其余字段全部恢复为初始状态
 func (c *Context) Reset() {
+	c.SetWriter(c.writer.ResponseWriter)
 	c.currentHandlerIndex = -1
 	c.Path = c.Request.URL.Path
 	c.Method = c.Request.Method
 	c.handlers = c.handlers[0:0]
+	c.Params = c.Params[0:0]
+	c.formCache = nil
+	c.MaxMultipartMemory = defaultMultipartMemory
+	c.releaseHooks = c.releaseHooks[0:0]
+	c.released = false
+	c.retained = false
+}
+
+// 注册释放钩子 请求处理完成后按注册的逆序执行 用于释放中间件持有的资源
+func (c *Context) OnRelease(hook func()) {
+	c.releaseHooks = append(c.releaseHooks, hook)
+}
+
+// 释放Context 执行释放钩子并清除对请求和响应的引用
+// 释放后的Context会被下一个请求复用 不能再使用 需要在goroutine中使用时先调用Copy或Retain
+// 释放后读取请求数据得到空值或ErrResponseDetached 写入响应返回ErrResponseDetached
+func (c *Context) Release() {
+	for i := len(c.releaseHooks) - 1; i >= 0; i-- {
+		c.releaseHooks[i]()
+		c.releaseHooks[i] = nil
+	}
+	c.releaseHooks = c.releaseHooks[0:0]
+	c.Writer = detachedWriter{}
+	c.writer.reset(detachedWriter{})
+	c.released = true
+	if c.retained {
+		// 保留的Context不会被复用 请求数据继续可用
+		return
+	}
+	c.Request = nil
+	// 参数值引用了请求路径 清除后才能释放请求
+	for i := range c.Params {
+		c.Params[i] = Param{}
+	}
+	c.Params = c.Params[0:0]
+	c.handlers = c.handlers[0:0]
+	c.currentHandlerIndex = -1
+	c.formCache = nil
+}
+
+// 是否已经释放
+func (c *Context) Released() bool {
+	return c.released
+}
+
+// 标记请求结束后仍会使用Context 例如传给handler中启动的goroutine
+// 保留的Context不会放回池中被其他请求复用 释放后与响应分离 请求数据仍然可以读取
+func (c *Context) Retain() {
+	c.retained = true
+}
+
+// 是否被保留 保留的Context释放后不能放回池中
+func (c *Context) Retained() bool {
+	return c.retained
+}
+
+// 复制Context 副本不会被复用 可以在请求结束后继续使用 例如在handler中启动的goroutine
+// 副本与响应分离 写入响应会返回ErrResponseDetached
+func (c *Context) Copy() *Context {
+	cp := &Context{
+		Writer:              detachedWriter{},
+		Request:             c.Request,
+		Path:                c.Path,
+		Method:              c.Method,
+		Params:              append(Params(nil), c.Params...),
+		currentHandlerIndex: -1,
+		MaxMultipartMemory:  c.MaxMultipartMemory,
+	}
+	if c.formCache != nil {
+		cp.formCache = make(map[string][]string, len(c.formCache))
+		for key, values := range c.formCache {
+			cp.formCache[key] = append([]string(nil), values...)
+		}
+	}
+	return cp
 }
 
 // post url-encode form
@@ -157,6 +295,9 @@ func (c *Context) form() {
 	if c.formCache == nil {
 		c.formCache = make(url.Values)
 		req := c.Request
+		if req == nil {
+			return
+		}
 		if err := req.ParseMultipartForm(c.MaxMultipartMemory); err != nil {
 			if err != http.ErrNotMultipart {
 				log.Printf("error on parse multipart form array: %v", err)
@@ -186,6 +327,9 @@ func (c *Context) PostValueDefault(key string, def string) string {
 }
 
 func (c *Context) ReadJSON(jsonObjectPtr interface{}) error {
+	if c.Request == nil {
+		return ErrResponseDetached
+	}
 	if c.Request.Body == nil {
 		return fmt.Errorf("unmarshal: empty body: %w", errors.New("not found"))
 	}
@@ -208,6 +352,9 @@ func GetBody(r *http.Request, resetBody bool) ([]byte, error) {
 }
 
 func (c *Context) GetBody() ([]byte, error) {
+	if c.Request == nil {
+		return nil, ErrResponseDetached
+	}
 	return GetBody(c.Request, true)
 }
 
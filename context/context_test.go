@@ -0,0 +1,114 @@
+package context
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newTestContext(method string, target string, body string) *Context {
+	ctx := NewContext()
+	ctx.Request = httptest.NewRequest(method, target, strings.NewReader(body))
+	ctx.SetWriter(httptest.NewRecorder())
+	ctx.Reset()
+	return ctx
+}
+
+func TestResetClearsState(t *testing.T) {
+	ctx := newTestContext(http.MethodPost, "/a", "name=glu")
+	ctx.Request.Header.Set(ContentTypeHeaderKey, ContentFormHeaderValue)
+	ctx.Params = append(ctx.Params, Param{Key: "id", Value: "1"})
+	ctx.MaxMultipartMemory = 1
+	ctx.SetHandlers(func(*Context) {})
+	if ctx.PostValue("name") != "glu" {
+		t.Fatal("form value should be parsed")
+	}
+	ctx.OnRelease(func() {})
+	ctx.Release()
+
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/b", nil)
+	ctx.SetWriter(httptest.NewRecorder())
+	ctx.Reset()
+	if ctx.Path != "/b" || ctx.Method != http.MethodGet {
+		t.Fatal("path and method should be reset")
+	}
+	if len(ctx.Params) != 0 || len(ctx.Handlers()) != 0 || ctx.formCache != nil || len(ctx.releaseHooks) != 0 {
+		t.Fatal("params, handlers, form cache and release hooks should be cleared")
+	}
+	if ctx.MaxMultipartMemory != defaultMultipartMemory || ctx.Released() {
+		t.Fatal("MaxMultipartMemory and released flag should be reset")
+	}
+	if ctx.PostValue("name") != "" {
+		t.Fatal("form values should not leak between requests")
+	}
+}
+
+func TestRelease(t *testing.T) {
+	ctx := newTestContext(http.MethodGet, "/a", "")
+	var order []int
+	ctx.OnRelease(func() {
+		order = append(order, 1)
+	})
+	ctx.OnRelease(func() {
+		order = append(order, 2)
+	})
+	ctx.Params = append(ctx.Params, Param{Key: "id", Value: "1"})
+	ctx.Release()
+	if len(order) != 2 || order[0] != 2 || order[1] != 1 {
+		t.Fatalf("release hooks should run in reverse order, got %v", order)
+	}
+	if !ctx.Released() || ctx.Request != nil || len(ctx.Params) != 0 {
+		t.Fatal("released context should drop request references")
+	}
+	if _, err := ctx.Writer.Write([]byte("x")); err != ErrResponseDetached {
+		t.Fatalf("write after release should fail, got %v", err)
+	}
+	// 释放后读取请求数据不会因为Request为nil而panic
+	if ctx.Query("a") != "" || ctx.Param("id") != "" || ctx.PostValue("name") != "" {
+		t.Fatal("released context should return empty values")
+	}
+	if _, err := ctx.GetBody(); err != ErrResponseDetached {
+		t.Fatalf("GetBody after release should fail, got %v", err)
+	}
+	var v struct{}
+	if err := ctx.ReadJSON(&v); err != ErrResponseDetached {
+		t.Fatalf("ReadJSON after release should fail, got %v", err)
+	}
+	if err := ctx.Bind(&v); err != ErrResponseDetached {
+		t.Fatalf("Bind after release should fail, got %v", err)
+	}
+}
+
+func TestRetain(t *testing.T) {
+	ctx := newTestContext(http.MethodGet, "/users/1?tab=posts", "")
+	ctx.Params = append(ctx.Params, Param{Key: "id", Value: "1"})
+	ctx.Retain()
+	ctx.Release()
+	if !ctx.Released() || !ctx.Retained() {
+		t.Fatal("retained context should be released but kept")
+	}
+	if ctx.Param("id") != "1" || ctx.Query("tab") != "posts" {
+		t.Fatal("retained context should keep request data")
+	}
+	if _, err := ctx.WriteString("x"); err != ErrResponseDetached {
+		t.Fatalf("retained context should not write response, got %v", err)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	ctx := newTestContext(http.MethodPost, "/users/1", url.Values{"name": {"glu"}}.Encode())
+	ctx.Request.Header.Set(ContentTypeHeaderKey, ContentFormHeaderValue)
+	ctx.Params = append(ctx.Params, Param{Key: "id", Value: "1"})
+	ctx.PostValue("name")
+	cp := ctx.Copy()
+	ctx.Release()
+
+	if cp.Released() || cp.Param("id") != "1" || cp.Path != "/users/1" || cp.PostValue("name") != "glu" {
+		t.Fatal("copy should keep request data after the original is released")
+	}
+	if _, err := cp.WriteString("x"); err != ErrResponseDetached {
+		t.Fatalf("copy should not write response, got %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package context
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// 记录状态码和写入字节数的ResponseWriter
+// WriteHeader只记录状态码 第一次写入body或调用WriteHeaderNow时才真正写出响应头
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.Pusher
+	io.StringWriter
+	// 响应状态码 未设置时为200
+	Status() int
+	// 已写入body的字节数
+	Size() int
+	// 响应头是否已经写出
+	Written() bool
+	// 立即写出响应头
+	WriteHeaderNow()
+	// 获取底层的http.ResponseWriter
+	Unwrap() http.ResponseWriter
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	size    int
+	written bool
+}
+
+// 包装http.ResponseWriter 已经是ResponseWriter时直接返回
+func NewResponseWriter(w http.ResponseWriter) ResponseWriter {
+	if rw, ok := w.(ResponseWriter); ok {
+		return rw
+	}
+	rw := &responseWriter{}
+	rw.reset(w)
+	return rw
+}
+
+func (w *responseWriter) reset(writer http.ResponseWriter) {
+	w.ResponseWriter = writer
+	w.status = http.StatusOK
+	w.size = 0
+	w.written = false
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.written {
+		w.written = true
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.WriteHeaderNow()
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) WriteString(s string) (int, error) {
+	w.WriteHeaderNow()
+	n, err := io.WriteString(w.ResponseWriter, s)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) Written() bool {
+	return w.written
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// 写出响应头后刷新缓冲 底层不支持时只写出响应头
+func (w *responseWriter) Flush() {
+	w.WriteHeaderNow()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// 接管连接后不再写出响应头
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.written = true
+	}
+	return conn, rw, err
+}
+
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// 与响应分离的Writer 用于已释放的Context和Context副本
+type detachedWriter struct{}
+
+func (detachedWriter) Header() http.Header {
+	return http.Header{}
+}
+
+func (detachedWriter) Write([]byte) (int, error) {
+	return 0, ErrResponseDetached
+}
+
+func (detachedWriter) WriteString(string) (int, error) {
+	return 0, ErrResponseDetached
+}
+
+func (detachedWriter) WriteHeader(int) {}
+
+func (detachedWriter) WriteHeaderNow() {}
+
+func (detachedWriter) Status() int {
+	return 0
+}
+
+func (detachedWriter) Size() int {
+	return 0
+}
+
+func (detachedWriter) Written() bool {
+	return true
+}
+
+func (detachedWriter) Unwrap() http.ResponseWriter {
+	return nil
+}
+
+func (detachedWriter) Flush() {}
+
+func (detachedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, ErrResponseDetached
+}
+
+func (detachedWriter) Push(string, *http.PushOptions) error {
+	return ErrResponseDetached
+}
@@ -0,0 +1,65 @@
+package context
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseWriterStatus(t *testing.T) {
+	ctx := newTestContext(http.MethodGet, "/", "")
+	recorder := ctx.Writer.Unwrap().(*httptest.ResponseRecorder)
+	if ctx.Writer.Status() != http.StatusOK || ctx.Writer.Written() || ctx.Writer.Size() != 0 {
+		t.Fatal("unexpected initial state")
+	}
+
+	ctx.StatusCode(http.StatusCreated)
+	// 设置状态码后仍然可以修改响应头
+	ctx.Header("X-Request-Id", "1")
+	if ctx.Writer.Written() || recorder.Flushed {
+		t.Fatal("StatusCode should not write the header immediately")
+	}
+	if _, err := ctx.WriteString("hello"); err != nil {
+		t.Fatal(err)
+	}
+	ctx.StatusCode(http.StatusInternalServerError)
+	if ctx.Writer.Status() != http.StatusCreated || ctx.Writer.Size() != 5 || !ctx.Writer.Written() {
+		t.Fatalf("unexpected state: %d %d %v", ctx.Writer.Status(), ctx.Writer.Size(), ctx.Writer.Written())
+	}
+	if recorder.Code != http.StatusCreated || recorder.Header().Get("X-Request-Id") != "1" {
+		t.Fatalf("unexpected response: %d %v", recorder.Code, recorder.Header())
+	}
+
+	// 复用时状态重新计算
+	ctx.Release()
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	ctx.SetWriter(httptest.NewRecorder())
+	ctx.Reset()
+	if ctx.Writer.Status() != http.StatusOK || ctx.Writer.Written() || ctx.Writer.Size() != 0 {
+		t.Fatal("writer state should be reset")
+	}
+}
+
+func TestResponseWriterInterfaces(t *testing.T) {
+	ctx := newTestContext(http.MethodGet, "/", "")
+	recorder := ctx.Writer.Unwrap().(*httptest.ResponseRecorder)
+	ctx.StatusCode(http.StatusAccepted)
+	ctx.Writer.Flush()
+	if !recorder.Flushed || recorder.Code != http.StatusAccepted {
+		t.Fatalf("Flush should write the header: %v %d", recorder.Flushed, recorder.Code)
+	}
+	if _, _, err := ctx.Writer.Hijack(); err != http.ErrNotSupported {
+		t.Fatalf("unexpected hijack error: %v", err)
+	}
+	if err := ctx.Writer.Push("/app.js", nil); err != http.ErrNotSupported {
+		t.Fatalf("unexpected push error: %v", err)
+	}
+	if NewResponseWriter(ctx.Writer) != ctx.Writer {
+		t.Fatal("ResponseWriter should not be wrapped twice")
+	}
+
+	ctx.Release()
+	if _, _, err := ctx.Writer.Hijack(); err != ErrResponseDetached {
+		t.Fatalf("released writer should be detached: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package context
+
+import (
+	"encoding/hex"
+	"errors"
+)
+
+var (
+	ErrInvalidUUID = errors.New("invalid uuid")
+)
+
+// RFC 4122格式的UUID
+type UUID [16]byte
+
+// 解析xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx格式的UUID 不区分大小写
+func ParseUUID(s string) (UUID, error) {
+	var uuid UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return uuid, ErrInvalidUUID
+	}
+	j := 0
+	for i := 0; i < len(s); {
+		if s[i] == '-' {
+			i++
+			continue
+		}
+		hi, ok1 := fromHexChar(s[i])
+		lo, ok2 := fromHexChar(s[i+1])
+		if !ok1 || !ok2 {
+			return UUID{}, ErrInvalidUUID
+		}
+		uuid[j] = hi<<4 | lo
+		i += 2
+		j++
+	}
+	return uuid, nil
+}
+
+func fromHexChar(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+func (u UUID) String() string {
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf)
+}
@@ -0,0 +1,19 @@
+package context
+
+import "testing"
+
+func TestParseUUID(t *testing.T) {
+	s := "1b4e28ba-2fa1-11d2-883f-0016d3cca427"
+	uuid, err := ParseUUID("1B4E28BA-2FA1-11D2-883F-0016D3CCA427")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uuid.String() != s {
+		t.Fatalf("unexpected uuid %s", uuid)
+	}
+	for _, invalid := range []string{"", "1b4e28ba2fa111d2883f0016d3cca427", "1b4e28ba-2fa1-11d2-883f-0016d3cca42g", "1b4e28ba-2fa1-11d2-883f_0016d3cca427"} {
+		if _, err := ParseUUID(invalid); err != ErrInvalidUUID {
+			t.Fatalf("%q should be invalid", invalid)
+		}
+	}
+}
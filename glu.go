@@ -3,41 +3,69 @@ package glu
 import (
 	"github.com/yyxing/glu/middleware/gluRecover"
 	"github.com/yyxing/glu/middleware/logger"
+	"github.com/yyxing/glu/proxy"
 	"github.com/yyxing/glu/router"
-	"log"
+	"net"
 	"net/http"
-	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 默认的优雅关闭等待时间
+	defaultShutdownTimeout = 10 * time.Second
 )
 
 type Engine struct {
 	*router.APIBuilder
-	proxyGroups []router.Group
+	// 优雅关闭时等待请求处理完成的最长时间
+	ShutdownTimeout time.Duration
+	// 检查证书文件是否更新的间隔 默认一分钟
+	CertReloadInterval time.Duration
+	// 明文HTTP/2(h2c) 适用于内部服务间通信
+	EnableH2C bool
+	// 调试模式 启动时打印路由表
+	Debug         bool
+	server        serverOptions
+	mu            sync.Mutex
+	servers       []*http.Server
+	listeners     []net.Listener
+	shutdownHooks []func()
 }
 
-func New() *Engine {
-	engine := &Engine{APIBuilder: router.NewAPIBuilder()}
+func New(options ...Option) *Engine {
+	engine := &Engine{APIBuilder: router.NewAPIBuilder(), ShutdownTimeout: defaultShutdownTimeout}
+	for _, option := range options {
+		option(engine)
+	}
 	return engine
 }
 
-func Default() *Engine {
-	engine := &Engine{APIBuilder: router.NewAPIBuilder()}
+func Default(options ...Option) *Engine {
+	engine := New(options...)
 	engine.Use(logger.New(), gluRecover.New())
 	return engine
 }
-func (e *Engine) Proxy(prefix string, handler http.Handler) {
-	proxyGroup := e.ReverseProxy(prefix, handler)
-	e.proxyGroups = append(e.proxyGroups, proxyGroup)
+
+// 将前缀下的请求转发给handler 与普通路由一样经过中间件
+func (e *Engine) Proxy(prefix string, handler http.Handler, options ...router.ProxyOption) router.Group {
+	return e.ReverseProxy(prefix, handler, options...)
 }
-func (e *Engine) Run(addr string) {
-	log.Printf("Now listening on: http://localhost%s\n", addr)
-	log.Fatal(http.ListenAndServe(addr, e))
+
+// 将前缀下的请求转发给注册中心中serviceName的健康实例 实例列表定时刷新 关闭服务时停止刷新
+// 默认按实例权重负载均衡 可以通过ServiceProxyOptions修改
+func (e *Engine) ProxyService(prefix string, resolver proxy.Resolver, serviceName string, options ...ServiceOption) (router.Group, error) {
+	o := &serviceOptions{proxy: []proxy.Option{proxy.WithBalancer(proxy.Weighted())}}
+	for _, option := range options {
+		option(o)
+	}
+	discovery, err := proxy.NewDiscovery(resolver, serviceName, o.scheme, o.interval, o.proxy...)
+	if err != nil {
+		return nil, err
+	}
+	e.OnShutdown(discovery.Close)
+	return e.Proxy(prefix, discovery, o.route...), nil
 }
 func (e *Engine) ServeHTTP(w http.ResponseWriter, request *http.Request) {
-	for _, group := range e.proxyGroups {
-		if strings.HasPrefix(request.URL.Path, group.Prefix()) {
-			group.ProxyHandler().ServeHTTP(w, request)
-			return
-		}
-	}
 	e.APIBuilder.HandleRequest(w, request)
 }
@@ -0,0 +1,325 @@
+package glu
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/yyxing/glu/cloud"
+	gluContext "github.com/yyxing/glu/context"
+	"github.com/yyxing/glu/proxy"
+	"github.com/yyxing/glu/router"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunContextShutdown(t *testing.T) {
+	engine := New()
+	engine.ShutdownTimeout = time.Second
+	var order []string
+	engine.OnShutdown(func() {
+		order = append(order, "first")
+	}, func() {
+		order = append(order, "second")
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- engine.RunContext(ctx, "127.0.0.1:0")
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("RunContext should return after ctx is canceled")
+	}
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Fatalf("shutdown hooks should run in order, got %v", order)
+	}
+	// 钩子只执行一次
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 2 {
+		t.Fatalf("shutdown hooks should run only once, got %v", order)
+	}
+}
+
+func waitAddrs(t *testing.T, engine *Engine, n int) []net.Addr {
+	for i := 0; i < 100; i++ {
+		if addrs := engine.Addrs(); len(addrs) == n {
+			return addrs
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("engine should listen on %d addresses", n)
+	return nil
+}
+
+func TestRunAddrs(t *testing.T) {
+	engine := New()
+	engine.Get("/ping", func(c *gluContext.Context) {
+		_, _ = c.WriteString("pong")
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- engine.RunAddrs(ctx, "127.0.0.1:0", "127.0.0.1:0")
+	}()
+	for _, addr := range waitAddrs(t, engine, 2) {
+		resp, err := http.Get("http://" + addr.String() + "/ping")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if string(body) != "pong" {
+			t.Fatalf("unexpected body %q on %s", body, addr)
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if len(engine.Addrs()) != 0 {
+		t.Fatal("addresses should be cleared after shutdown")
+	}
+}
+
+func TestRunUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "glu-unix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "glu.sock")
+	engine := New()
+	engine.Get("/ping", func(c *gluContext.Context) {
+		_, _ = c.WriteString("pong")
+	})
+	done := make(chan error, 1)
+	go func() {
+		done <- engine.RunUnix(path)
+	}()
+	waitAddrs(t, engine, 1)
+	client := http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://unix/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != "pong" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunUnixInUse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "glu-unix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "glu.sock")
+	engine := New()
+	done := make(chan error, 1)
+	go func() {
+		done <- engine.RunUnix(path)
+	}()
+	waitAddrs(t, engine, 1)
+	// socket仍在使用时不能被第二个实例抢占
+	if err := New().RunUnix(path); !errors.Is(err, syscall.EADDRINUSE) {
+		t.Fatalf("expected address in use, got %v", err)
+	}
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	// 遗留的socket文件可以被清理
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = ln.Close()
+	engine = New()
+	go func() {
+		done <- engine.RunUnix(path)
+	}()
+	waitAddrs(t, engine, 1)
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServeContextOnlyStopsOwnServer(t *testing.T) {
+	engine := New()
+	engine.ShutdownTimeout = time.Second
+	hooks := 0
+	engine.OnShutdown(func() {
+		hooks++
+	})
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	done1, done2 := make(chan error, 1), make(chan error, 1)
+	go func() {
+		done1 <- engine.RunContext(ctx1, "127.0.0.1:0")
+	}()
+	waitAddrs(t, engine, 1)
+	go func() {
+		done2 <- engine.RunContext(ctx2, "127.0.0.1:0")
+	}()
+	addrs := waitAddrs(t, engine, 2)
+	cancel1()
+	if err := <-done1; err != nil {
+		t.Fatal(err)
+	}
+	remain := waitAddrs(t, engine, 1)
+	if hooks != 0 {
+		t.Fatal("hooks should not run while another server is running")
+	}
+	if remain[0].String() != addrs[1].String() {
+		t.Fatalf("the second server should keep running, got %v", remain)
+	}
+	resp, err := http.Get("http://" + remain[0].String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	cancel2()
+	if err := <-done2; err != nil {
+		t.Fatal(err)
+	}
+	if hooks != 1 {
+		t.Fatalf("hooks should run once after the last server stops, got %d", hooks)
+	}
+}
+
+// 固定返回一个实例的注册中心
+type stubResolver struct {
+	mu       sync.Mutex
+	calls    int
+	instance cloud.Instance
+}
+
+func (r *stubResolver) SelectInstances(string, bool) ([]cloud.Instance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	return []cloud.Instance{r.instance}, nil
+}
+
+func (r *stubResolver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls
+}
+
+func TestProxyServiceOptions(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer backend.Close()
+	host, port, _ := net.SplitHostPort(backend.Listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	resolver := &stubResolver{instance: cloud.Instance{Ip: host, Port: p, Weight: 1, Healthy: true}}
+
+	engine := New()
+	_, err := engine.ProxyService("/users", resolver, "users",
+		ServiceRefreshInterval(10*time.Millisecond),
+		ServiceProxyOptions(proxy.WithTimeout(50*time.Millisecond)),
+		ServiceRouteOptions(router.ProxyStripPrefix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	if w.Body.String() != "/1" {
+		t.Fatalf("prefix should be stripped, got %q", w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/slow", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("proxy timeout should apply, got %d", w.Code)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if resolver.count() < 3 {
+		t.Fatalf("instances should refresh at the configured interval, got %d calls", resolver.count())
+	}
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerOptions(t *testing.T) {
+	errorLog := log.New(ioutil.Discard, "", 0)
+	engine := New(
+		WithReadTimeout(time.Second),
+		WithReadHeaderTimeout(2*time.Second),
+		WithWriteTimeout(3*time.Second),
+		WithIdleTimeout(4*time.Second),
+		WithMaxHeaderBytes(1024),
+		WithErrorLog(errorLog),
+		WithServer(func(srv *http.Server) {
+			srv.WriteTimeout = 5 * time.Second
+		}),
+		WithShutdownTimeout(6*time.Second),
+	)
+	srv := engine.newServer()
+	if srv.ReadTimeout != time.Second || srv.ReadHeaderTimeout != 2*time.Second || srv.IdleTimeout != 4*time.Second {
+		t.Fatal("timeouts should be applied to server")
+	}
+	if srv.WriteTimeout != 5*time.Second {
+		t.Fatal("WithServer should run after other options")
+	}
+	if srv.MaxHeaderBytes != 1024 || srv.ErrorLog != errorLog {
+		t.Fatal("MaxHeaderBytes and ErrorLog should be applied to server")
+	}
+	if engine.ShutdownTimeout != 6*time.Second {
+		t.Fatal("shutdown timeout should be applied to engine")
+	}
+}
+
+func TestPrintRoutes(t *testing.T) {
+	engine := New(WithDebug())
+	engine.Get("/ping", func(c *gluContext.Context) {})
+	var buf bytes.Buffer
+	engine.printRoutes(&buf)
+	if !strings.Contains(buf.String(), "GET     /ping") || !strings.Contains(buf.String(), "(1 handlers)") {
+		t.Fatalf("unexpected route table %q", buf.String())
+	}
+}
@@ -25,6 +25,6 @@ func New() context.Handler {
 	return func(c *context.Context) {
 		t := time.Now()
 		c.Next()
-		log.Infof("[%d] %s in %v", 200, c.Request.RequestURI, time.Since(t))
+		log.Infof("[%d] %s in %v", c.Writer.Status(), c.Request.RequestURI, time.Since(t))
 	}
 }
@@ -0,0 +1,202 @@
+package glu
+
+import (
+	"github.com/yyxing/glu/proxy"
+	"github.com/yyxing/glu/router"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Engine配置项 在New/Default时传入
+type Option func(*Engine)
+
+// 底层http.Server的配置 零值表示使用net/http的默认行为
+type serverOptions struct {
+	readTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	maxHeaderBytes    int
+	errorLog          *log.Logger
+	connState         func(net.Conn, http.ConnState)
+	configure         []func(*http.Server)
+}
+
+func (o *serverOptions) apply(srv *http.Server) {
+	srv.ReadTimeout = o.readTimeout
+	srv.ReadHeaderTimeout = o.readHeaderTimeout
+	srv.WriteTimeout = o.writeTimeout
+	srv.IdleTimeout = o.idleTimeout
+	srv.MaxHeaderBytes = o.maxHeaderBytes
+	srv.ErrorLog = o.errorLog
+	srv.ConnState = o.connState
+	for _, configure := range o.configure {
+		configure(srv)
+	}
+}
+
+// 读取整个请求(包括body)的超时时间
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(e *Engine) {
+		e.server.readTimeout = timeout
+	}
+}
+
+// 读取请求头的超时时间 可防止slowloris类的慢速攻击
+func WithReadHeaderTimeout(timeout time.Duration) Option {
+	return func(e *Engine) {
+		e.server.readHeaderTimeout = timeout
+	}
+}
+
+// 写入响应的超时时间
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(e *Engine) {
+		e.server.writeTimeout = timeout
+	}
+}
+
+// keep-alive连接的空闲超时时间
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(e *Engine) {
+		e.server.idleTimeout = timeout
+	}
+}
+
+// 请求头的最大字节数
+func WithMaxHeaderBytes(size int) Option {
+	return func(e *Engine) {
+		e.server.maxHeaderBytes = size
+	}
+}
+
+// 服务内部错误(如连接错误、handler panic)的日志输出
+func WithErrorLog(logger *log.Logger) Option {
+	return func(e *Engine) {
+		e.server.errorLog = logger
+	}
+}
+
+// 连接状态变化的回调
+func WithConnState(hook func(net.Conn, http.ConnState)) Option {
+	return func(e *Engine) {
+		e.server.connState = hook
+	}
+}
+
+// 直接修改底层http.Server 在其他配置之后执行
+func WithServer(configure func(*http.Server)) Option {
+	return func(e *Engine) {
+		e.server.configure = append(e.server.configure, configure)
+	}
+}
+
+// 优雅关闭时等待请求处理完成的最长时间
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(e *Engine) {
+		e.ShutdownTimeout = timeout
+	}
+}
+
+// 检查证书文件是否更新的间隔
+func WithCertReloadInterval(interval time.Duration) Option {
+	return func(e *Engine) {
+		e.CertReloadInterval = interval
+	}
+}
+
+// 开启明文HTTP/2(h2c)
+func WithH2C() Option {
+	return func(e *Engine) {
+		e.EnableH2C = true
+	}
+}
+
+// 路径存在但请求方法不匹配时是否返回405 默认开启
+func WithHandleMethodNotAllowed(enabled bool) Option {
+	return func(e *Engine) {
+		e.Router().HandleMethodNotAllowed = enabled
+	}
+}
+
+// 未注册HEAD时是否使用GET的处理并丢弃响应body 默认开启
+func WithHandleHEAD(enabled bool) Option {
+	return func(e *Engine) {
+		e.Router().HandleHEAD = enabled
+	}
+}
+
+// 未注册OPTIONS时是否自动响应 默认开启
+func WithHandleOPTIONS(enabled bool) Option {
+	return func(e *Engine) {
+		e.Router().HandleOPTIONS = enabled
+	}
+}
+
+// 开启调试模式 启动时打印路由表
+func WithDebug() Option {
+	return func(e *Engine) {
+		e.Debug = true
+	}
+}
+
+// 路径末尾多出或缺少/时是否重定向 默认开启
+func WithRedirectTrailingSlash(enabled bool) Option {
+	return func(e *Engine) {
+		e.Router().RedirectTrailingSlash = enabled
+	}
+}
+
+// 是否清理路径并忽略大小写查找路由后重定向 默认关闭
+func WithRedirectFixedPath(enabled bool) Option {
+	return func(e *Engine) {
+		e.Router().RedirectFixedPath = enabled
+	}
+}
+
+// 是否使用URL.RawPath匹配路由 默认关闭
+func WithUseRawPath(enabled bool) Option {
+	return func(e *Engine) {
+		e.Router().UseRawPath = enabled
+	}
+}
+
+// ProxyService的配置项
+type ServiceOption func(*serviceOptions)
+
+type serviceOptions struct {
+	scheme   string
+	interval time.Duration
+	proxy    []proxy.Option
+	route    []router.ProxyOption
+}
+
+// 转发到实例使用的协议 默认http
+func ServiceScheme(scheme string) ServiceOption {
+	return func(o *serviceOptions) {
+		o.scheme = scheme
+	}
+}
+
+// 实例列表的刷新间隔 为0时使用proxy.NewDiscovery的默认间隔
+func ServiceRefreshInterval(interval time.Duration) ServiceOption {
+	return func(o *serviceOptions) {
+		o.interval = interval
+	}
+}
+
+// 转发的配置 如负载均衡策略、超时和被动健康检查 在默认配置之后生效
+func ServiceProxyOptions(options ...proxy.Option) ServiceOption {
+	return func(o *serviceOptions) {
+		o.proxy = append(o.proxy, options...)
+	}
+}
+
+// 挂载到路由的配置 如去掉前缀后转发
+func ServiceRouteOptions(options ...router.ProxyOption) ServiceOption {
+	return func(o *serviceOptions) {
+		o.route = append(o.route, options...)
+	}
+}
@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"hash/fnv"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// 负载均衡策略 从可用的后端服务中选择一个 upstreams不为空
+type Balancer interface {
+	Next(upstreams []*Upstream, r *http.Request) *Upstream
+}
+
+// 轮询
+func RoundRobin() Balancer {
+	return &roundRobin{}
+}
+
+type roundRobin struct {
+	next uint64
+}
+
+func (b *roundRobin) Next(upstreams []*Upstream, r *http.Request) *Upstream {
+	n := atomic.AddUint64(&b.next, 1) - 1
+	return upstreams[n%uint64(len(upstreams))]
+}
+
+// 平滑加权轮询 按Upstream.Weight分配请求
+func Weighted() Balancer {
+	return &weighted{}
+}
+
+type weighted struct {
+	mu sync.Mutex
+}
+
+func (b *weighted) Next(upstreams []*Upstream, r *http.Request) *Upstream {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	total := 0
+	var best *Upstream
+	for _, u := range upstreams {
+		w := u.weight()
+		total += w
+		u.current += w
+		if best == nil || u.current > best.current {
+			best = u
+		}
+	}
+	best.current -= total
+	return best
+}
+
+// 最少连接 按正在处理的请求数与权重的比值选择
+func LeastConn() Balancer {
+	return leastConn{}
+}
+
+type leastConn struct{}
+
+func (leastConn) Next(upstreams []*Upstream, r *http.Request) *Upstream {
+	best := upstreams[0]
+	for _, u := range upstreams[1:] {
+		// a/wa < b/wb 等价于 a*wb < b*wa
+		if u.Active()*int64(best.weight()) < best.Active()*int64(u.weight()) {
+			best = u
+		}
+	}
+	return best
+}
+
+// 一致性哈希(最高随机权重) 相同key的请求转发到同一后端 后端增减时只影响部分key
+// key为nil时使用客户端IP
+func ConsistentHash(key func(r *http.Request) string) Balancer {
+	if key == nil {
+		key = clientIP
+	}
+	return consistentHash{key: key}
+}
+
+type consistentHash struct {
+	key func(r *http.Request) string
+}
+
+func (b consistentHash) Next(upstreams []*Upstream, r *http.Request) *Upstream {
+	key := b.key(r)
+	var best *Upstream
+	var max uint64
+	for _, u := range upstreams {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(key))
+		_, _ = h.Write([]byte(u.URL.String()))
+		if score := mix(h.Sum64()); best == nil || score > max {
+			best, max = u, score
+		}
+	}
+	return best
+}
+
+// 打散哈希值 使不同key的得分分布更均匀
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func upstreams(t *testing.T, weights ...int) []*Upstream {
+	list := make([]*Upstream, 0, len(weights))
+	for i, w := range weights {
+		u, err := NewUpstream("http://127.0.0.1:"+strconv.Itoa(9000+i), w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list = append(list, u)
+	}
+	return list
+}
+
+func TestRoundRobin(t *testing.T) {
+	list := upstreams(t, 1, 1, 1)
+	b := RoundRobin()
+	r := httptest.NewRequest("GET", "/", nil)
+	for i := 0; i < 6; i++ {
+		if got := b.Next(list, r); got != list[i%3] {
+			t.Fatalf("round %d: got %s", i, got)
+		}
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	list := upstreams(t, 5, 1, 1)
+	b := Weighted()
+	r := httptest.NewRequest("GET", "/", nil)
+	counts := map[*Upstream]int{}
+	for i := 0; i < 70; i++ {
+		counts[b.Next(list, r)]++
+	}
+	if counts[list[0]] != 50 || counts[list[1]] != 10 || counts[list[2]] != 10 {
+		t.Fatalf("unexpected distribution: %d %d %d", counts[list[0]], counts[list[1]], counts[list[2]])
+	}
+	// 平滑加权 权重高的不会连续占满
+	seq := ""
+	for i := 0; i < 7; i++ {
+		seq += strconv.Itoa(indexOf(list, b.Next(list, r)))
+	}
+	if seq != "0010200" {
+		t.Fatalf("weighted selection is not smooth: %s", seq)
+	}
+}
+
+func indexOf(list []*Upstream, u *Upstream) int {
+	for i, v := range list {
+		if v == u {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestLeastConn(t *testing.T) {
+	list := upstreams(t, 1, 1, 2)
+	list[0].active = 3
+	list[1].active = 1
+	list[2].active = 3
+	r := httptest.NewRequest("GET", "/", nil)
+	if got := LeastConn().Next(list, r); got != list[1] {
+		t.Fatalf("got %s", got)
+	}
+	// 权重为2时3个连接相当于1.5
+	list[1].active = 2
+	if got := LeastConn().Next(list, r); got != list[2] {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func TestConsistentHash(t *testing.T) {
+	list := upstreams(t, 1, 1, 1, 1)
+	b := ConsistentHash(func(r *http.Request) string {
+		return r.Header.Get("X-User")
+	})
+	assigned := map[string]*Upstream{}
+	for i := 0; i < 100; i++ {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("X-User", strconv.Itoa(i))
+		u := b.Next(list, r)
+		if again := b.Next(list, r); again != u {
+			t.Fatalf("key %d mapped to %s and %s", i, u, again)
+		}
+		assigned[strconv.Itoa(i)] = u
+	}
+	// 去掉一个后端 只有原本分配给它的key会迁移
+	removed := list[3]
+	for key, u := range assigned {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("X-User", key)
+		got := b.Next(list[:3], r)
+		if u != removed && got != u {
+			t.Fatalf("key %s moved from %s to %s", key, u, got)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"github.com/yyxing/glu/cloud"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 默认的实例列表刷新间隔
+const defaultRefreshInterval = 10 * time.Second
+
+// 实例来源 cloud.NamingClient实现了该接口
+type Resolver interface {
+	SelectInstances(serviceName string, healthyOnly bool) ([]cloud.Instance, error)
+}
+
+// 从注册中心获取后端服务的反向代理 定时刷新实例列表
+type Discovery struct {
+	*Proxy
+	resolver    Resolver
+	serviceName string
+	// 转发使用的协议
+	scheme    string
+	interval  time.Duration
+	mu        sync.Mutex
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// 创建服务发现代理 首次获取实例失败时返回错误
+// scheme为转发使用的协议 为空时使用http interval为0时使用默认的刷新间隔
+func NewDiscovery(resolver Resolver, serviceName string, scheme string, interval time.Duration, options ...Option) (*Discovery, error) {
+	if scheme == "" {
+		scheme = "http"
+	}
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+	d := &Discovery{
+		Proxy:       New(nil, options...),
+		resolver:    resolver,
+		serviceName: serviceName,
+		scheme:      scheme,
+		interval:    interval,
+		stop:        make(chan struct{}),
+	}
+	if err := d.Refresh(); err != nil {
+		return nil, err
+	}
+	go d.watch()
+	return d, nil
+}
+
+// 服务名
+func (d *Discovery) ServiceName() string {
+	return d.serviceName
+}
+
+// 立即从注册中心刷新实例列表 失败时保留原有列表
+// 地址不变的实例复用原有的Upstream 保留连接数和健康状态
+func (d *Discovery) Refresh() error {
+	instances, err := d.resolver.SelectInstances(d.serviceName, true)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	existing := make(map[string]*Upstream)
+	for _, u := range d.Upstreams() {
+		existing[u.URL.Host] = u
+	}
+	seen := make(map[string]bool, len(instances))
+	upstreams := make([]*Upstream, 0, len(instances))
+	for _, instance := range instances {
+		// 权重为0的实例不接收流量
+		weight := instanceWeight(instance.Weight)
+		host := net.JoinHostPort(instance.Ip, strconv.Itoa(instance.Port))
+		if !instance.Healthy || weight == 0 || seen[host] {
+			continue
+		}
+		seen[host] = true
+		// 权重变化时创建新的Upstream 避免并发修改
+		u, ok := existing[host]
+		if !ok || u.Weight != weight {
+			u, err = NewUpstream(d.scheme+"://"+host, weight)
+			if err != nil {
+				return err
+			}
+		}
+		upstreams = append(upstreams, u)
+	}
+	d.SetUpstreams(upstreams)
+	return nil
+}
+
+// 停止刷新实例列表
+func (d *Discovery) Close() {
+	d.closeOnce.Do(func() {
+		close(d.stop)
+	})
+}
+
+func (d *Discovery) watch() {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			if err := d.Refresh(); err != nil {
+				d.logf("proxy: refresh instances of %s: %v", d.serviceName, err)
+			}
+		}
+	}
+}
+
+// 注册中心的权重可以是小数 放大100倍后取整以保留比例
+func instanceWeight(weight float64) int {
+	if weight <= 0 {
+		return 0
+	}
+	w := int(math.Round(weight * 100))
+	if w < 1 {
+		w = 1
+	}
+	return w
+}
@@ -0,0 +1,130 @@
+package proxy
+
+import (
+	"encoding/json"
+	"github.com/yyxing/glu/cloud"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 本地模拟的注册中心
+type fakeNaming struct {
+	mu        sync.Mutex
+	instances []cloud.Instance
+}
+
+func (f *fakeNaming) set(instances ...cloud.Instance) {
+	f.mu.Lock()
+	f.instances = instances
+	f.mu.Unlock()
+}
+
+func (f *fakeNaming) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != cloud.ServicePath+"/list" || r.URL.Query().Get("serviceName") != "users" {
+		http.NotFound(w, r)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_ = json.NewEncoder(w).Encode(cloud.ServiceInfo{Name: "users", Hosts: f.instances})
+}
+
+func instanceOf(t *testing.T, server *httptest.Server, weight float64, healthy bool) cloud.Instance {
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := strconv.Atoi(port)
+	return cloud.Instance{Ip: host, Port: p, ServiceName: "users", Weight: weight, Healthy: healthy}
+}
+
+func namingClient(t *testing.T, server *httptest.Server) *cloud.NamingClient {
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := strconv.ParseUint(port, 10, 64)
+	return cloud.NewNamingClient([]cloud.ServerConfig{{IpAddr: host, Port: p, Scheme: "http"}}, time.Second, "test")
+}
+
+func TestDiscovery(t *testing.T) {
+	a, b, c := backend("a"), backend("b"), backend("c")
+	defer a.Close()
+	defer b.Close()
+	defer c.Close()
+	naming := &fakeNaming{}
+	naming.set(instanceOf(t, a, 3, true), instanceOf(t, b, 1, true), instanceOf(t, c, 5, false))
+	server := httptest.NewServer(naming)
+	defer server.Close()
+
+	d, err := NewDiscovery(namingClient(t, server), "users", "", 10*time.Millisecond, WithBalancer(Weighted()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	count := func(n int) map[string]int {
+		seen := map[string]int{}
+		for i := 0; i < n; i++ {
+			w := httptest.NewRecorder()
+			d.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+			seen[w.Header().Get("X-Backend")]++
+		}
+		return seen
+	}
+	// 不健康的实例不转发 按权重分配
+	if seen := count(8); seen["a"] != 6 || seen["b"] != 2 || seen["c"] != 0 {
+		t.Fatalf("unexpected distribution: %v", seen)
+	}
+
+	// 实例上下线后自动更新
+	naming.set(instanceOf(t, b, 1, true), instanceOf(t, c, 1, true))
+	deadline := time.Now().Add(2 * time.Second)
+	for len(d.Upstreams()) != 2 || d.Upstreams()[1].URL.Host != c.Listener.Addr().String() {
+		if time.Now().After(deadline) {
+			t.Fatalf("upstreams not refreshed: %v", d.Upstreams())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if seen := count(4); seen["b"] != 2 || seen["c"] != 2 {
+		t.Fatalf("unexpected distribution after refresh: %v", seen)
+	}
+}
+
+func TestDiscoveryRefreshError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	if _, err := NewDiscovery(namingClient(t, server), "users", "", time.Minute); err == nil {
+		t.Fatal("expected error when naming server fails")
+	}
+}
+
+func TestDiscoveryScheme(t *testing.T) {
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	defer backend.Close()
+	naming := &fakeNaming{}
+	naming.set(instanceOf(t, backend, 1, true))
+	server := httptest.NewServer(naming)
+	defer server.Close()
+
+	d, err := NewDiscovery(namingClient(t, server), "users", "https", time.Minute, WithTransport(backend.Client().Transport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if upstreams := d.Upstreams(); len(upstreams) != 1 || upstreams[0].URL.Scheme != "https" {
+		t.Fatalf("upstreams should use https: %v", upstreams)
+	}
+	w := httptest.NewRecorder()
+	d.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("request over https failed: %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// Proxy配置项 在New时传入
+type Option func(*Proxy)
+
+// 负载均衡策略 默认轮询
+func WithBalancer(balancer Balancer) Option {
+	return func(p *Proxy) {
+		p.balancer = balancer
+	}
+}
+
+// 单次转发(包括读取响应)的超时时间 Upstream.Timeout优先 超时返回504
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *Proxy) {
+		p.timeout = timeout
+	}
+}
+
+// 被动健康检查 连续失败maxFails次后摘除ejectFor时长 maxFails为0时关闭
+// 连接失败、超时以及502/503/504响应计为失败
+func WithPassiveHealth(maxFails int, ejectFor time.Duration) Option {
+	return func(p *Proxy) {
+		p.maxFails = maxFails
+		p.ejectFor = ejectFor
+	}
+}
+
+// 转发使用的Transport 默认http.DefaultTransport
+func WithTransport(transport http.RoundTripper) Option {
+	return func(p *Proxy) {
+		p.proxy.Transport = transport
+	}
+}
+
+// 信任请求中已有的X-Forwarded-*头 适用于前面还有一层可信代理的情况
+func WithTrustForwarded() Option {
+	return func(p *Proxy) {
+		p.trustForwarded = true
+	}
+}
+
+// 转发错误的日志 默认使用log包
+func WithErrorLog(logger *log.Logger) Option {
+	return func(p *Proxy) {
+		p.errorLog = logger
+	}
+}
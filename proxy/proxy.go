@@ -0,0 +1,229 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// 默认连续失败3次后摘除
+	defaultMaxFails = 3
+	// 默认摘除10秒
+	defaultEjectFor = 10 * time.Second
+)
+
+// 请求上下文中保存选中的后端服务
+type upstreamKey struct{}
+
+// 负载均衡反向代理 实现http.Handler 可直接用于Engine.Proxy
+type Proxy struct {
+	balancer Balancer
+	// 默认的单次转发超时时间
+	timeout time.Duration
+	// 被动健康检查 连续失败maxFails次后摘除ejectFor时长
+	maxFails int
+	ejectFor time.Duration
+	// 是否信任请求中已有的X-Forwarded-*头
+	trustForwarded bool
+	errorLog       *log.Logger
+	mu             sync.RWMutex
+	upstreams      []*Upstream
+	proxy          *httputil.ReverseProxy
+}
+
+func New(upstreams []*Upstream, options ...Option) *Proxy {
+	p := &Proxy{
+		balancer:  RoundRobin(),
+		maxFails:  defaultMaxFails,
+		ejectFor:  defaultEjectFor,
+		upstreams: upstreams,
+	}
+	p.proxy = &httputil.ReverseProxy{
+		Director:       p.director,
+		ModifyResponse: p.modifyResponse,
+		ErrorHandler:   p.errorHandler,
+	}
+	for _, option := range options {
+		option(p)
+	}
+	p.proxy.ErrorLog = p.errorLog
+	return p
+}
+
+// 根据地址列表创建后端服务 权重均为1
+func Parse(targets ...string) ([]*Upstream, error) {
+	upstreams := make([]*Upstream, 0, len(targets))
+	for _, target := range targets {
+		u, err := NewUpstream(target, 1)
+		if err != nil {
+			return nil, err
+		}
+		upstreams = append(upstreams, u)
+	}
+	return upstreams, nil
+}
+
+// 获取当前的后端服务列表
+func (p *Proxy) Upstreams() []*Upstream {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.upstreams
+}
+
+// 替换后端服务列表 正在处理的请求不受影响
+func (p *Proxy) SetUpstreams(upstreams []*Upstream) {
+	p.mu.Lock()
+	p.upstreams = upstreams
+	p.mu.Unlock()
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u := p.pick(r)
+	if u == nil {
+		http.Error(w, fmt.Sprintf("503 SERVICE UNAVAILABLE: no healthy upstream for %s", r.URL.Path), http.StatusServiceUnavailable)
+		return
+	}
+	atomic.AddInt64(&u.active, 1)
+	defer atomic.AddInt64(&u.active, -1)
+
+	ctx := context.WithValue(r.Context(), upstreamKey{}, u)
+	timeout := u.Timeout
+	if timeout == 0 {
+		timeout = p.timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	p.proxy.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// 从可用的后端服务中选择 全部不可用时返回nil
+func (p *Proxy) pick(r *http.Request) *Upstream {
+	all := p.Upstreams()
+	healthy := make([]*Upstream, 0, len(all))
+	for _, u := range all {
+		if u.Healthy() {
+			healthy = append(healthy, u)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+	return p.balancer.Next(healthy, r)
+}
+
+func (p *Proxy) director(req *http.Request) {
+	u := req.Context().Value(upstreamKey{}).(*Upstream)
+	target := u.URL
+	req.URL.Scheme = target.Scheme
+	req.URL.Host = target.Host
+	req.URL.Path, req.URL.RawPath = joinURLPath(target, req.URL)
+	if target.RawQuery == "" || req.URL.RawQuery == "" {
+		req.URL.RawQuery = target.RawQuery + req.URL.RawQuery
+	} else {
+		req.URL.RawQuery = target.RawQuery + "&" + req.URL.RawQuery
+	}
+	if _, ok := req.Header["User-Agent"]; !ok {
+		// 不使用Go默认的User-Agent
+		req.Header.Set("User-Agent", "")
+	}
+	p.setForwarded(req)
+}
+
+// 设置X-Forwarded-Host和X-Forwarded-Proto X-Forwarded-For由httputil追加客户端IP
+// 不信任客户端时丢弃请求中已有的值 防止伪造
+func (p *Proxy) setForwarded(req *http.Request) {
+	if !p.trustForwarded {
+		req.Header.Del("X-Forwarded-For")
+		req.Header.Del("X-Forwarded-Host")
+		req.Header.Del("X-Forwarded-Proto")
+	}
+	if req.Header.Get("X-Forwarded-Host") == "" {
+		req.Header.Set("X-Forwarded-Host", req.Host)
+	}
+	if req.Header.Get("X-Forwarded-Proto") == "" {
+		proto := "http"
+		if req.TLS != nil {
+			proto = "https"
+		}
+		req.Header.Set("X-Forwarded-Proto", proto)
+	}
+}
+
+// 网关类错误视为后端失败 其余响应视为后端正常
+func (p *Proxy) modifyResponse(resp *http.Response) error {
+	u := resp.Request.Context().Value(upstreamKey{}).(*Upstream)
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		u.fail(p.maxFails, p.ejectFor)
+	default:
+		u.succeed()
+	}
+	return nil
+}
+
+func (p *Proxy) errorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	u := r.Context().Value(upstreamKey{}).(*Upstream)
+	// 客户端主动断开不算后端失败
+	if !errors.Is(err, context.Canceled) {
+		u.fail(p.maxFails, p.ejectFor)
+	}
+	p.logf("proxy: %s %s via %s: %v", r.Method, r.URL.Path, u, err)
+	status := http.StatusBadGateway
+	if errors.Is(err, context.DeadlineExceeded) {
+		status = http.StatusGatewayTimeout
+	}
+	w.WriteHeader(status)
+}
+
+func (p *Proxy) logf(format string, args ...interface{}) {
+	if p.errorLog != nil {
+		p.errorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
+
+// 拼接目标路径和请求路径
+func joinURLPath(a, b *url.URL) (path, rawpath string) {
+	if a.RawPath == "" && b.RawPath == "" {
+		return singleJoiningSlash(a.Path, b.Path), ""
+	}
+	// 与singleJoiningSlash相同 但根据编码后的路径判断是否需要添加/
+	apath := a.EscapedPath()
+	bpath := b.EscapedPath()
+
+	aslash := strings.HasSuffix(apath, "/")
+	bslash := strings.HasPrefix(bpath, "/")
+
+	switch {
+	case aslash && bslash:
+		return a.Path + b.Path[1:], apath + bpath[1:]
+	case !aslash && !bslash:
+		return a.Path + "/" + b.Path, apath + "/" + bpath
+	}
+	return a.Path + b.Path, apath + bpath
+}
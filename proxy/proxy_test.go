@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func backend(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Backend", name)
+		w.Header().Set("X-Got-Path", r.URL.Path)
+		w.Header().Set("X-Got-Query", r.URL.RawQuery)
+		w.Header().Set("X-Got-For", r.Header.Get("X-Forwarded-For"))
+		w.Header().Set("X-Got-Host", r.Header.Get("X-Forwarded-Host"))
+		w.Header().Set("X-Got-Proto", r.Header.Get("X-Forwarded-Proto"))
+	}))
+}
+
+func quietLog() Option {
+	return WithErrorLog(log.New(ioutil.Discard, "", 0))
+}
+
+func TestProxyForward(t *testing.T) {
+	a, b := backend("a"), backend("b")
+	defer a.Close()
+	defer b.Close()
+	list, err := Parse(a.URL+"/base?from=proxy", b.URL+"/base?from=proxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := New(list)
+
+	seen := map[string]int{}
+	for i := 0; i < 4; i++ {
+		r := httptest.NewRequest("GET", "http://gateway.example.com/users?id=1", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		r.Header.Set("X-Forwarded-For", "6.6.6.6")
+		r.Header.Set("X-Forwarded-Host", "evil.example.com")
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, r)
+		h := w.Header()
+		seen[h.Get("X-Backend")]++
+		if h.Get("X-Got-Path") != "/base/users" || h.Get("X-Got-Query") != "from=proxy&id=1" {
+			t.Fatalf("unexpected upstream request: %s?%s", h.Get("X-Got-Path"), h.Get("X-Got-Query"))
+		}
+		if h.Get("X-Got-For") != "10.0.0.1" || h.Get("X-Got-Host") != "gateway.example.com" || h.Get("X-Got-Proto") != "http" {
+			t.Fatalf("unexpected forwarded headers: %q %q %q", h.Get("X-Got-For"), h.Get("X-Got-Host"), h.Get("X-Got-Proto"))
+		}
+	}
+	if seen["a"] != 2 || seen["b"] != 2 {
+		t.Fatalf("requests not balanced: %v", seen)
+	}
+}
+
+func TestProxyTrustForwarded(t *testing.T) {
+	a := backend("a")
+	defer a.Close()
+	list, _ := Parse(a.URL)
+	p := New(list, WithTrustForwarded())
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.2:1234"
+	r.Header.Set("X-Forwarded-For", "1.1.1.1")
+	r.Header.Set("X-Forwarded-Proto", "https")
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	if got := w.Header().Get("X-Got-For"); got != "1.1.1.1, 10.0.0.2" {
+		t.Fatalf("unexpected X-Forwarded-For: %q", got)
+	}
+	if got := w.Header().Get("X-Got-Proto"); got != "https" {
+		t.Fatalf("unexpected X-Forwarded-Proto: %q", got)
+	}
+}
+
+func TestPassiveHealth(t *testing.T) {
+	good := backend("good")
+	defer good.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer bad.Close()
+	// 已关闭的后端 连接失败
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	list, _ := Parse(good.URL, bad.URL, down.URL)
+	p := New(list, WithPassiveHealth(2, time.Hour), quietLog())
+	for i := 0; i < 12; i++ {
+		p.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+	if list[1].Healthy() || list[2].Healthy() {
+		t.Fatal("failing upstreams should be ejected")
+	}
+	if !list[0].Healthy() {
+		t.Fatal("healthy upstream should not be ejected")
+	}
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Header().Get("X-Backend") != "good" {
+			t.Fatalf("request sent to ejected upstream: %d", w.Code)
+		}
+	}
+
+	// 全部摘除时返回503
+	p.SetUpstreams(list[1:])
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+}
+
+func TestUpstreamTimeout(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	list, _ := Parse(slow.URL)
+	list[0].Timeout = 20 * time.Millisecond
+	p := New(list, WithTimeout(time.Minute), quietLog())
+	start := time.Now()
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504, got %d", w.Code)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatal("upstream timeout not applied")
+	}
+	if list[0].Active() != 0 {
+		t.Fatalf("active count not released: %d", list[0].Active())
+	}
+}
@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"fmt"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+// 后端服务
+type Upstream struct {
+	// 转发目标 请求路径拼接在目标路径之后
+	URL *url.URL
+	// 权重 用于加权轮询和最少连接 小于1时按1处理
+	Weight int
+	// 单次转发的超时时间 0表示使用Proxy的默认值
+	Timeout time.Duration
+	// 正在处理的请求数
+	active int64
+	// 连续失败次数
+	fails int32
+	// 摘除截止时间(UnixNano) 0表示未被摘除
+	ejectedUntil int64
+	// 平滑加权轮询的当前权重 由balancer加锁访问
+	current int
+}
+
+// 根据地址创建后端服务 如 http://127.0.0.1:8080/base
+func NewUpstream(target string, weight int) (*Upstream, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("proxy: invalid upstream %q", target)
+	}
+	return &Upstream{URL: u, Weight: weight}, nil
+}
+
+// 正在处理的请求数
+func (u *Upstream) Active() int64 {
+	return atomic.LoadInt64(&u.active)
+}
+
+// 是否可用 被摘除的服务在摘除时间结束前不可用
+func (u *Upstream) Healthy() bool {
+	until := atomic.LoadInt64(&u.ejectedUntil)
+	return until == 0 || time.Now().UnixNano() >= until
+}
+
+func (u *Upstream) weight() int {
+	if u.Weight < 1 {
+		return 1
+	}
+	return u.Weight
+}
+
+// 记录一次失败 连续失败maxFails次后摘除ejectFor时长
+func (u *Upstream) fail(maxFails int, ejectFor time.Duration) {
+	if maxFails <= 0 {
+		return
+	}
+	if int(atomic.AddInt32(&u.fails, 1)) >= maxFails {
+		atomic.StoreInt32(&u.fails, 0)
+		atomic.StoreInt64(&u.ejectedUntil, time.Now().Add(ejectFor).UnixNano())
+	}
+}
+
+// 记录一次成功 清空失败次数
+func (u *Upstream) succeed() {
+	atomic.StoreInt32(&u.fails, 0)
+	atomic.StoreInt64(&u.ejectedUntil, 0)
+}
+
+func (u *Upstream) String() string {
+	return u.URL.String()
+}
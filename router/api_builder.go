@@ -1,17 +1,35 @@
 package router
 
 import (
+	"fmt"
 	"github.com/yyxing/glu/context"
 	"net/http"
+	"strings"
 	"sync"
 )
 
+// Any注册的请求方法
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodHead, http.MethodOptions, http.MethodDelete, http.MethodConnect,
+	http.MethodTrace,
+}
+
+// 中间件组合规则:
+// 路由的处理链 = 各级父分组的中间件(由外到内) + 分组自身的中间件 + 路由的handler
+// 分组的中间件按Group创建时传入和Use调用的顺序排列
+// 中间件在Use时对已注册和之后注册的路由都生效
 type APIBuilder struct {
+	// 父分组 顶层为nil
+	parent *APIBuilder
+	// 分组自身的中间件 不包含父分组的中间件
 	middlewares  context.Handlers
 	prefix       string
 	router       *Router
 	proxyHandler http.Handler
 	pool         sync.Pool
+	// 分组所属的Host 为空表示不限制Host
+	host string
 }
 
 func NewAPIBuilder() *APIBuilder {
@@ -23,17 +41,46 @@ func NewAPIBuilder() *APIBuilder {
 			return context.NewContext()
 		}},
 	}
+	api.router.setNoRoute(api, context.Handlers{notFound})
+	api.router.setNoMethod(api, context.Handlers{methodNotAllowed})
 	return api
 }
-func (api *APIBuilder) addRoute(method string, pattern string, handler context.Handler) {
-	if api.prefix[len(api.prefix)-1] == '/' {
-		if pattern[0] == '/' {
-			pattern = pattern[1:]
-		}
+func (api *APIBuilder) addRoute(method string, pattern string, handlers context.Handlers) *Route {
+	if len(handlers) == 0 {
+		panic(fmt.Sprintf("router: no handler for %s %s", method, pattern))
+	}
+	route := &Route{
+		Method:   method,
+		Path:     api.fullPath(pattern),
+		Host:     api.host,
+		group:    api,
+		handlers: joinHandlers(nil, handlers),
+		router:   api.router,
+	}
+	api.router.addRoute(route)
+	return route
+}
+
+// 加上分组前缀的完整路径
+func (api *APIBuilder) fullPath(pattern string) string {
+	if pattern == "" {
+		return api.prefix
+	}
+	return jsonPrefixPath(api.prefix, pattern)
+}
+
+// 删除分组下的路由 pattern与注册时相同 路由不存在时返回false
+// 可以在服务运行时调用 正在处理的请求不受影响
+func (api *APIBuilder) RemoveRoute(method string, pattern string) bool {
+	return api.router.RemoveRoute(method, api.fullPath(pattern))
+}
+
+// 获取分组完整的中间件链 父分组的中间件在前 返回新的切片
+func (api *APIBuilder) chain() context.Handlers {
+	if api.parent == nil {
+		return joinHandlers(nil, api.middlewares)
 	}
-	pattern = api.prefix + pattern
-	handlers := append(api.middlewares, handler)
-	api.router.AddRouter(method, pattern, handlers...)
+	return joinHandlers(api.parent.chain(), api.middlewares)
 }
 func joinHandlers(h1 context.Handlers, h2 context.Handlers) context.Handlers {
 	nowLen := len(h1)
@@ -54,72 +101,137 @@ func jsonPrefixPath(a, b string) string {
 	return b
 }
 func (api *APIBuilder) Group(prefix string, handlers ...context.Handler) Group {
-	middlewares := joinHandlers(api.middlewares, handlers)
 	prefix = jsonPrefixPath(api.prefix, prefix)
 	return &APIBuilder{
-		middlewares: middlewares,
+		parent:      api,
+		middlewares: joinHandlers(nil, handlers),
 		prefix:      prefix,
 		router:      api.router,
+		host:        api.host,
 	}
 }
-func (api *APIBuilder) ReverseProxy(prefix string, handler http.Handler) Group {
-	prefix = jsonPrefixPath(api.prefix, prefix)
-	return &APIBuilder{
-		middlewares:  api.middlewares,
-		prefix:       prefix,
-		router:       api.router,
-		proxyHandler: handler,
-	}
-}
+
+// 添加分组中间件 已注册的路由也会生效
 func (api *APIBuilder) Use(handler ...context.Handler) {
+	main := api.router.main()
+	main.mu.Lock()
+	defer main.mu.Unlock()
 	api.middlewares = append(api.middlewares, handler...)
+	main.refresh()
+}
+func (api *APIBuilder) Get(pattern string, handlers ...context.Handler) *Route {
+	return api.addRoute(http.MethodGet, pattern, handlers)
+}
+
+func (api *APIBuilder) Head(pattern string, handlers ...context.Handler) *Route {
+	return api.addRoute(http.MethodHead, pattern, handlers)
 }
-func (api *APIBuilder) Get(pattern string, handler context.Handler) {
-	api.addRoute(http.MethodGet, pattern, handler)
+
+func (api *APIBuilder) Delete(pattern string, handlers ...context.Handler) *Route {
+	return api.addRoute(http.MethodDelete, pattern, handlers)
+}
+
+func (api *APIBuilder) Post(pattern string, handlers ...context.Handler) *Route {
+	return api.addRoute(http.MethodPost, pattern, handlers)
+}
+
+func (api *APIBuilder) Options(pattern string, handlers ...context.Handler) *Route {
+	return api.addRoute(http.MethodOptions, pattern, handlers)
 }
 
-func (api *APIBuilder) Head(pattern string, handler context.Handler) {
-	api.addRoute(http.MethodHead, pattern, handler)
+func (api *APIBuilder) Put(pattern string, handlers ...context.Handler) *Route {
+	return api.addRoute(http.MethodPut, pattern, handlers)
 }
 
-func (api *APIBuilder) Delete(pattern string, handler context.Handler) {
-	api.addRoute(http.MethodDelete, pattern, handler)
+func (api *APIBuilder) Patch(pattern string, handlers ...context.Handler) *Route {
+	return api.addRoute(http.MethodPatch, pattern, handlers)
 }
 
-func (api *APIBuilder) Post(pattern string, handler context.Handler) {
-	api.addRoute(http.MethodPost, pattern, handler)
+func (api *APIBuilder) Trace(pattern string, handlers ...context.Handler) *Route {
+	return api.addRoute(http.MethodTrace, pattern, handlers)
 }
 
-func (api *APIBuilder) Options(pattern string, handler context.Handler) {
-	api.addRoute(http.MethodOptions, pattern, handler)
+func (api *APIBuilder) Connect(pattern string, handlers ...context.Handler) *Route {
+	return api.addRoute(http.MethodConnect, pattern, handlers)
 }
 
-func (api *APIBuilder) Put(pattern string, handler context.Handler) {
-	api.addRoute(http.MethodPut, pattern, handler)
+func (api *APIBuilder) Handle(method string, pattern string, handlers ...context.Handler) *Route {
+	return api.addRoute(method, pattern, handlers)
 }
 
-func (api *APIBuilder) Patch(pattern string, handler context.Handler) {
-	api.addRoute(http.MethodPatch, pattern, handler)
+// 为所有标准请求方法注册同一处理链
+func (api *APIBuilder) Any(pattern string, handlers ...context.Handler) []*Route {
+	return api.Match(anyMethods, pattern, handlers...)
 }
 
-func (api *APIBuilder) Trace(pattern string, handler context.Handler) {
-	api.addRoute(http.MethodTrace, pattern, handler)
+// 为指定的请求方法注册同一处理链
+func (api *APIBuilder) Match(methods []string, pattern string, handlers ...context.Handler) []*Route {
+	routes := make([]*Route, 0, len(methods))
+	for _, method := range methods {
+		routes = append(routes, api.addRoute(strings.ToUpper(method), pattern, handlers))
+	}
+	return routes
 }
 
-func (api *APIBuilder) Connect(pattern string, handler context.Handler) {
-	api.addRoute(http.MethodConnect, pattern, handler)
+// 设置分组下未匹配到路由时的处理 会先经过分组的中间件
+func (api *APIBuilder) NoRoute(handlers ...context.Handler) {
+	api.router.setNoRoute(api, handlers)
 }
 
-func (api *APIBuilder) Handle(method string, pattern string, handler context.Handler) {
-	api.addRoute(method, pattern, handler)
+// 设置分组下路径存在但请求方法不匹配时的处理 会先经过分组的中间件 响应头中已设置Allow
+func (api *APIBuilder) NoMethod(handlers ...context.Handler) {
+	api.router.setNoMethod(api, handlers)
 }
 
 func (api *APIBuilder) HandleRequest(w http.ResponseWriter, request *http.Request) {
+	ctx := api.acquireContext(w, request)
+	defer api.releaseContext(ctx)
+	router := api.router
+	t := router.load()
+	// 预分配参数空间 复用Context时不再分配内存
+	if cap(ctx.Params) < t.maxParams {
+		ctx.Params = make(context.Params, 0, t.maxParams)
+	}
+	if len(t.hosts) > 0 {
+		if h := t.matchHost(request.Host, &ctx.Params); h != nil {
+			router = h.builder.router
+		}
+	}
+	router.Serve(ctx)
+	// 只设置了状态码没有写入body时 在请求结束前写出响应头
+	ctx.Writer.WriteHeaderNow()
+}
+
+// 从池中获取Context并重置
+func (api *APIBuilder) acquireContext(w http.ResponseWriter, request *http.Request) *context.Context {
 	ctx := api.pool.Get().(*context.Context)
 	ctx.Request = request
-	ctx.Writer = w
+	ctx.SetWriter(w)
 	ctx.Reset()
-	api.router.Serve(ctx)
+	return ctx
+}
+
+// 释放Context并放回池中 被保留的Context不再复用
+func (api *APIBuilder) releaseContext(ctx *context.Context) {
+	ctx.Release()
+	if !ctx.Retained() {
+		api.pool.Put(ctx)
+	}
+}
+
+// 按注册顺序返回所有路由的信息
+func (api *APIBuilder) Routes() []RouteInfo {
+	return api.router.Routes()
+}
+
+// 根据路由名字生成请求路径 params依次填充路径中的参数
+func (api *APIBuilder) URL(name string, params ...interface{}) (string, error) {
+	return api.router.URL(name, params...)
+}
+
+// 获取底层路由表
+func (api *APIBuilder) Router() *Router {
+	return api.router
 }
 
 func (api *APIBuilder) Prefix() string {
@@ -0,0 +1,451 @@
+package router
+
+import (
+	"github.com/yyxing/glu/context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serve(api *APIBuilder, method string, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	api.HandleRequest(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func write(body string) context.Handler {
+	return func(c *context.Context) {
+		_, _ = c.WriteString(body)
+	}
+}
+
+func TestNoRoute(t *testing.T) {
+	api := NewAPIBuilder()
+	var trace []string
+	api.Use(func(c *context.Context) {
+		trace = append(trace, "global")
+		c.Next()
+	})
+	api.Get("/hello", write("hello"))
+	v1 := api.Group("/v1", func(c *context.Context) {
+		trace = append(trace, "v1")
+		c.Next()
+	})
+	v1.Get("/users", write("users"))
+	v1.NoRoute(func(c *context.Context) {
+		c.StatusCode(http.StatusNotFound)
+		_, _ = c.WriteString("v1 not found")
+	})
+
+	w := serve(api, http.MethodGet, "/missing")
+	if w.Code != http.StatusNotFound || w.Body.String() != "404 NOT FOUND: /missing\n" {
+		t.Fatalf("unexpected default 404 response: %d %q", w.Code, w.Body.String())
+	}
+	if len(trace) != 1 || trace[0] != "global" {
+		t.Fatalf("global middleware should run on 404, got %v", trace)
+	}
+
+	trace = nil
+	w = serve(api, http.MethodGet, "/v1/missing")
+	if w.Code != http.StatusNotFound || w.Body.String() != "v1 not found" {
+		t.Fatalf("group NoRoute should be used: %d %q", w.Code, w.Body.String())
+	}
+	if len(trace) != 2 || trace[0] != "global" || trace[1] != "v1" {
+		t.Fatalf("group middleware should run on group NoRoute, got %v", trace)
+	}
+
+	// 前缀按路径段匹配
+	w = serve(api, http.MethodGet, "/v1missing")
+	if w.Body.String() != "404 NOT FOUND: /v1missing\n" {
+		t.Fatalf("group NoRoute should not match by raw prefix: %q", w.Body.String())
+	}
+}
+
+func TestSamePrefixGroups(t *testing.T) {
+	api := NewAPIBuilder()
+	var trace []string
+	mark := func(name string) context.Handler {
+		return func(c *context.Context) {
+			trace = append(trace, name)
+			c.Next()
+		}
+	}
+	x := api.Group("/api", mark("x"))
+	x.NoRoute(func(c *context.Context) {
+		c.StatusCode(http.StatusNotFound)
+		_, _ = c.WriteString("x not found")
+	})
+	y := api.Group("/api", mark("y"))
+	y.Get("/users", write("users"))
+	y.NoMethod(func(c *context.Context) {
+		c.StatusCode(http.StatusMethodNotAllowed)
+		_, _ = c.WriteString("y not allowed")
+	})
+
+	w := serve(api, http.MethodGet, "/api/missing")
+	if w.Body.String() != "x not found" || strings.Join(trace, ",") != "x" {
+		t.Fatalf("NoRoute should only run its own group middleware: %q %v", w.Body.String(), trace)
+	}
+	trace = nil
+	w = serve(api, http.MethodPost, "/api/users")
+	if w.Body.String() != "y not allowed" || strings.Join(trace, ",") != "y" {
+		t.Fatalf("NoMethod should only run its own group middleware: %q %v", w.Body.String(), trace)
+	}
+}
+
+func TestNoMethod(t *testing.T) {
+	api := NewAPIBuilder()
+	api.Get("/users/:id", write("get"))
+	api.Put("/users/:id", write("put"))
+	api.Delete("/users/:id", write("delete"))
+
+	w := serve(api, http.MethodPost, "/users/1")
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "DELETE, GET, HEAD, OPTIONS, PUT" {
+		t.Fatalf("unexpected Allow header %q", allow)
+	}
+
+	api.NoMethod(func(c *context.Context) {
+		c.StatusCode(http.StatusMethodNotAllowed)
+		_, _ = c.WriteString("custom")
+	})
+	w = serve(api, http.MethodPost, "/users/1")
+	if w.Body.String() != "custom" || w.Header().Get("Allow") == "" {
+		t.Fatalf("custom NoMethod should be used with Allow header: %q", w.Body.String())
+	}
+
+	w = serve(api, http.MethodPost, "/posts/1")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unknown path should be 404, got %d", w.Code)
+	}
+
+	api.router.HandleMethodNotAllowed = false
+	w = serve(api, http.MethodPost, "/users/1")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("405 handling disabled should fall back to 404, got %d", w.Code)
+	}
+}
+
+func TestAutoHeadAndOptions(t *testing.T) {
+	api := NewAPIBuilder()
+	api.Get("/users/:id", func(c *context.Context) {
+		c.Header("X-User", c.Param("id"))
+		_, _ = c.WriteString("user")
+	})
+	api.Post("/users/:id", write("post"))
+	api.Get("/custom", write("get"))
+	api.Head("/custom", func(c *context.Context) {
+		c.Header("X-Custom", "head")
+	})
+	api.Options("/custom", write("options"))
+
+	// 响应body由net/http丢弃
+	w := serve(api, http.MethodHead, "/users/1")
+	if w.Code != http.StatusOK || w.Header().Get("X-User") != "1" {
+		t.Fatalf("HEAD should run GET chain: %d", w.Code)
+	}
+	w = serve(api, http.MethodOptions, "/users/1")
+	if w.Code != http.StatusNoContent || w.Header().Get("Allow") != "GET, HEAD, OPTIONS, POST" {
+		t.Fatalf("unexpected OPTIONS response: %d %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	// 显式注册的HEAD和OPTIONS优先
+	w = serve(api, http.MethodHead, "/custom")
+	if w.Header().Get("X-Custom") != "head" {
+		t.Fatal("registered HEAD route should override GET")
+	}
+	w = serve(api, http.MethodOptions, "/custom")
+	if w.Body.String() != "options" {
+		t.Fatal("registered OPTIONS route should override automatic response")
+	}
+
+	api.router.HandleHEAD = false
+	api.router.HandleOPTIONS = false
+	w = serve(api, http.MethodHead, "/users/1")
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, POST" {
+		t.Fatalf("HEAD should be 405 when disabled: %d %q", w.Code, w.Header().Get("Allow"))
+	}
+	w = serve(api, http.MethodOptions, "/users/1")
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("OPTIONS should be 405 when disabled, got %d", w.Code)
+	}
+}
+
+func TestAutoHeadContentLength(t *testing.T) {
+	api := NewAPIBuilder()
+	api.Get("/x", write("hello world"))
+	server := httptest.NewServer(http.HandlerFunc(api.HandleRequest))
+	defer server.Close()
+
+	lengths := make(map[string]string)
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		req, _ := http.NewRequest(method, server.URL+"/x", nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if method == http.MethodHead && len(body) != 0 {
+			t.Fatalf("HEAD response should have no body, got %q", body)
+		}
+		lengths[method] = resp.Header.Get("Content-Length")
+	}
+	if lengths[http.MethodGet] != "11" || lengths[http.MethodHead] != lengths[http.MethodGet] {
+		t.Fatalf("HEAD should have the same Content-Length as GET: %v", lengths)
+	}
+}
+
+// 自动响应的OPTIONS和重定向经过路由所在分组的中间件
+func TestAutoResponsesUseRouteGroup(t *testing.T) {
+	api := NewAPIBuilder()
+	var trace []string
+	mark := func(name string) context.Handler {
+		return func(c *context.Context) {
+			trace = append(trace, name)
+			c.Next()
+		}
+	}
+	api.Use(mark("global"))
+	api.Group("/api", mark("cors")).Get("/x", write("x"))
+	api.Group("/api", mark("auth")).NoRoute(write("missing"))
+
+	w := serve(api, http.MethodOptions, "/api/x")
+	if w.Code != http.StatusNoContent || strings.Join(trace, ",") != "global,cors" {
+		t.Fatalf("OPTIONS should run the route group middleware: %d %v", w.Code, trace)
+	}
+	trace = nil
+	w = serve(api, http.MethodGet, "/api/x/")
+	if w.Code != http.StatusMovedPermanently || strings.Join(trace, ",") != "global,cors" {
+		t.Fatalf("redirect should run the route group middleware: %d %v", w.Code, trace)
+	}
+	trace = nil
+	serve(api, http.MethodGet, "/api/y")
+	if strings.Join(trace, ",") != "global,auth" {
+		t.Fatalf("NoRoute should run its own group middleware: %v", trace)
+	}
+}
+
+func logMiddleware(c *context.Context) {
+	c.Next()
+}
+
+func listUsers(c *context.Context) {}
+
+func TestRoutes(t *testing.T) {
+	api := NewAPIBuilder()
+	api.Use(logMiddleware)
+	api.Get("/ping", write("pong"))
+	v1 := api.Group("/v1", logMiddleware)
+	v1.Get("/users", listUsers)
+
+	routes := api.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 routes, got %d", len(routes))
+	}
+	route := routes[1]
+	if route.Method != http.MethodGet || route.Path != "/v1/users" {
+		t.Fatalf("unexpected route %s %s", route.Method, route.Path)
+	}
+	if route.Middlewares != 2 || len(route.Handlers) != 3 {
+		t.Fatalf("unexpected handler count: %d middlewares, %d handlers", route.Middlewares, len(route.Handlers))
+	}
+	if route.Handler != "github.com/yyxing/glu/router.listUsers" {
+		t.Fatalf("unexpected handler name %s", route.Handler)
+	}
+	if route.Handlers[0] != "github.com/yyxing/glu/router.logMiddleware" {
+		t.Fatalf("unexpected middleware name %s", route.Handlers[0])
+	}
+}
+
+func record(steps *[]string, name string) context.Handler {
+	return func(c *context.Context) {
+		*steps = append(*steps, name)
+		c.Next()
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	api := NewAPIBuilder()
+	var steps []string
+	api.Use(record(&steps, "A"))
+	g := api.Group("/g", record(&steps, "B"))
+	g.Get("/x", record(&steps, "H"))
+	inner := g.Group("/inner", record(&steps, "E"))
+	inner.Get("/y", record(&steps, "H"))
+	// 后添加的中间件对已注册的路由同样生效
+	api.Use(record(&steps, "C"))
+	g.Use(record(&steps, "D"))
+	api.Get("/z", record(&steps, "H"))
+
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"/g/x", "A,C,B,D,H"},
+		{"/g/inner/y", "A,C,B,D,E,H"},
+		{"/z", "A,C,H"},
+		// 分组的404同样使用最新的中间件链
+		{"/missing", "A,C"},
+	}
+	for _, c := range cases {
+		steps = nil
+		serve(api, http.MethodGet, c.path)
+		if got := strings.Join(steps, ","); got != c.want {
+			t.Errorf("%s: got %s, want %s", c.path, got, c.want)
+		}
+	}
+
+	for _, info := range api.Routes() {
+		if info.Path == "/g/inner/y" && info.Middlewares != 5 {
+			t.Fatalf("unexpected middleware count: %d", info.Middlewares)
+		}
+	}
+}
+
+func TestMiddlewareNoAliasing(t *testing.T) {
+	api := NewAPIBuilder()
+	var steps []string
+	// 容量大于长度时 append会共享底层数组
+	middlewares := make(context.Handlers, 0, 8)
+	middlewares = append(middlewares, record(&steps, "A"))
+	g := api.Group("/g", middlewares...)
+	g.Get("/a", record(&steps, "a"))
+	g.Get("/b", record(&steps, "b"))
+	middlewares = append(middlewares, record(&steps, "X"))
+
+	steps = nil
+	serve(api, http.MethodGet, "/g/a")
+	if got := strings.Join(steps, ","); got != "A,a" {
+		t.Fatalf("got %s", got)
+	}
+	steps = nil
+	serve(api, http.MethodGet, "/g/b")
+	if got := strings.Join(steps, ","); got != "A,b" {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func TestRouteHandlers(t *testing.T) {
+	api := NewAPIBuilder()
+	var steps []string
+	api.Use(record(&steps, "global"))
+	api.Get("/admin", record(&steps, "auth"), record(&steps, "validate"), record(&steps, "admin"))
+	api.Get("/public", record(&steps, "public"))
+
+	steps = nil
+	serve(api, http.MethodGet, "/admin")
+	if got := strings.Join(steps, ","); got != "global,auth,validate,admin" {
+		t.Fatalf("got %s", got)
+	}
+	steps = nil
+	serve(api, http.MethodGet, "/public")
+	if got := strings.Join(steps, ","); got != "global,public" {
+		t.Fatalf("route middleware should not leak to other routes, got %s", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("registering a route without handlers should panic")
+		}
+	}()
+	api.Get("/empty")
+}
+
+func TestAnyAndMatch(t *testing.T) {
+	api := NewAPIBuilder()
+	if routes := api.Any("/any", write("any")); len(routes) != len(anyMethods) {
+		t.Fatalf("Any registered %d routes", len(routes))
+	}
+	api.Match([]string{"get", http.MethodPost}, "/match", write("match"))
+
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete, http.MethodPatch} {
+		if w := serve(api, method, "/any"); w.Body.String() != "any" {
+			t.Fatalf("%s /any: %d %q", method, w.Code, w.Body.String())
+		}
+	}
+	if w := serve(api, http.MethodPost, "/match"); w.Body.String() != "match" {
+		t.Fatalf("POST /match: %d %q", w.Code, w.Body.String())
+	}
+	w := serve(api, http.MethodPut, "/match")
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("PUT /match: %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS, POST" {
+		t.Fatalf("unexpected Allow header: %q", allow)
+	}
+}
+
+func TestMiddlewareSeesStatus(t *testing.T) {
+	api := NewAPIBuilder()
+	var status, size int
+	api.Use(func(c *context.Context) {
+		c.Next()
+		status, size = c.Writer.Status(), c.Writer.Size()
+	})
+	api.Get("/created", func(c *context.Context) {
+		c.StatusCode(http.StatusCreated)
+		_, _ = c.WriteString("done")
+	})
+	api.Get("/empty", func(c *context.Context) {
+		c.StatusCode(http.StatusAccepted)
+	})
+	api.Use(func(c *context.Context) {
+		c.Next()
+		// 未写入body时仍然可以修改响应头
+		if !c.Writer.Written() {
+			c.Header("X-Late", "1")
+		}
+	})
+
+	if serve(api, http.MethodGet, "/created"); status != http.StatusCreated || size != 4 {
+		t.Fatalf("got %d %d", status, size)
+	}
+	if serve(api, http.MethodGet, "/missing"); status != http.StatusNotFound {
+		t.Fatalf("got %d", status)
+	}
+	w := serve(api, http.MethodGet, "/empty")
+	if status != http.StatusAccepted || w.Code != http.StatusAccepted || w.Header().Get("X-Late") != "1" {
+		t.Fatalf("got %d %d %q", status, w.Code, w.Header().Get("X-Late"))
+	}
+}
+
+func TestReleasedContextNotReused(t *testing.T) {
+	api := NewAPIBuilder()
+	type result struct {
+		id       string
+		released bool
+		err      error
+	}
+	done := make(chan struct{})
+	results := make(chan result, 1)
+	api.Get("/users/:id", func(c *context.Context) {
+		if c.Query("async") == "" {
+			_, _ = c.WriteString(c.Param("id"))
+			return
+		}
+		// 在goroutine中继续使用Context
+		c.Retain()
+		go func() {
+			<-done
+			_, err := c.WriteString("late")
+			results <- result{id: c.Param("id"), released: c.Released(), err: err}
+		}()
+	})
+	serve(api, http.MethodGet, "/users/1?async=1")
+	// 后续请求不会复用被保留的Context
+	for i := 0; i < 10; i++ {
+		if w := serve(api, http.MethodGet, "/users/2"); w.Body.String() != "2" {
+			t.Fatalf("unexpected body %q", w.Body.String())
+		}
+	}
+	close(done)
+	r := <-results
+	if r.id != "1" || !r.released || r.err != context.ErrResponseDetached {
+		t.Fatalf("retained context should keep its own request and stay detached: %+v", r)
+	}
+}
@@ -0,0 +1,127 @@
+package router
+
+import (
+	"github.com/yyxing/glu/context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type nopWriter struct {
+	header http.Header
+}
+
+func (w *nopWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *nopWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *nopWriter) WriteHeader(int) {}
+
+func nopHandler(*context.Context) {}
+
+var benchRoutes = []string{
+	"/",
+	"/users",
+	"/users/:id",
+	"/users/:id/posts",
+	"/users/:id/posts/:post",
+	"/users/:id<int>/orders",
+	"/repos/:owner/:repo/issues/:number/comments",
+	"/repos/:owner/:repo/pulls",
+	"/assets/*filepath",
+	"/search",
+	"/orgs/:org/teams",
+}
+
+func newBenchRouter() *Router {
+	r := NewRouter()
+	for _, pattern := range benchRoutes {
+		r.AddRouter(http.MethodGet, pattern, nopHandler)
+	}
+	return r
+}
+
+// 复用同一个Context 模拟池化后的请求处理
+func serveFunc(r *Router, path string) func() {
+	ctx := context.NewContext()
+	ctx.Request = httptest.NewRequest(http.MethodGet, path, nil)
+	ctx.SetWriter(&nopWriter{header: make(http.Header)})
+	ctx.Params = make(context.Params, 0, r.load().maxParams)
+	return func() {
+		ctx.Reset()
+		r.Serve(ctx)
+	}
+}
+
+func TestServeAllocs(t *testing.T) {
+	r := newBenchRouter()
+	for _, path := range []string{"/users", "/users/1/posts/2", "/repos/a/b/issues/3/comments", "/assets/js/app.js"} {
+		serve := serveFunc(r, path)
+		if allocs := testing.AllocsPerRun(100, serve); allocs != 0 {
+			t.Fatalf("serve %s should not allocate, got %v allocs", path, allocs)
+		}
+	}
+}
+
+func benchmarkServe(b *testing.B, path string) {
+	serve := serveFunc(newBenchRouter(), path)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		serve()
+	}
+}
+
+func BenchmarkStaticRoute(b *testing.B) {
+	benchmarkServe(b, "/users")
+}
+
+func BenchmarkParamRoute(b *testing.B) {
+	benchmarkServe(b, "/users/1/posts/2")
+}
+
+func BenchmarkConstraintRoute(b *testing.B) {
+	benchmarkServe(b, "/users/1/orders")
+}
+
+func BenchmarkMultiParamRoute(b *testing.B) {
+	benchmarkServe(b, "/repos/yyxing/glu/issues/13/comments")
+}
+
+func BenchmarkCatchAllRoute(b *testing.B) {
+	benchmarkServe(b, "/assets/js/vendor/app.js")
+}
+
+func TestHandleRequestAllocs(t *testing.T) {
+	api := NewAPIBuilder()
+	for _, pattern := range benchRoutes {
+		api.Get(pattern, nopHandler)
+	}
+	w := &nopWriter{header: make(http.Header)}
+	request := httptest.NewRequest(http.MethodGet, "/users/1/posts/2", nil)
+	// 池化的Context被复用 预热后不再分配内存 GC清空池时偶尔会重新分配
+	api.HandleRequest(w, request)
+	if allocs := testing.AllocsPerRun(100, func() {
+		api.HandleRequest(w, request)
+	}); allocs >= 1 {
+		t.Fatalf("HandleRequest should not allocate, got %v allocs", allocs)
+	}
+}
+
+func BenchmarkHandleRequest(b *testing.B) {
+	api := NewAPIBuilder()
+	for _, pattern := range benchRoutes {
+		api.Get(pattern, nopHandler)
+	}
+	w := &nopWriter{header: make(http.Header)}
+	request := httptest.NewRequest(http.MethodGet, "/users/1/posts/2", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		api.HandleRequest(w, request)
+	}
+}
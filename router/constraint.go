@@ -0,0 +1,115 @@
+package router
+
+import (
+	"fmt"
+	"github.com/yyxing/glu/context"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// 路由参数的约束 例如 /users/:id<int> 不满足约束的请求会继续尝试其他路由
+type constraint struct {
+	// 约束的原始写法 用于判断是否冲突
+	expr  string
+	match func(value string) bool
+}
+
+var (
+	constraintsMu sync.RWMutex
+	constraints   = map[string]func(value string) bool{
+		"int":   isInt,
+		"uint":  isUint,
+		"alpha": isAlpha,
+		"uuid": func(value string) bool {
+			_, err := context.ParseUUID(value)
+			return err == nil
+		},
+	}
+)
+
+// 注册自定义的参数约束 在路由中以 :name<约束名> 使用
+func RegisterConstraint(name string, match func(value string) bool) {
+	constraintsMu.Lock()
+	defer constraintsMu.Unlock()
+	constraints[name] = match
+}
+
+// 约束的写法 没有约束时为空字符串
+func (c *constraint) String() string {
+	if c == nil {
+		return ""
+	}
+	return "<" + c.expr + ">"
+}
+
+// 解析约束表达式 支持已注册的约束名和regex(...)
+func parseConstraint(expr string) (*constraint, error) {
+	if strings.HasPrefix(expr, "regex(") && strings.HasSuffix(expr, ")") {
+		// 参数值需要完整匹配
+		re, err := regexp.Compile("^(?:" + expr[len("regex("):len(expr)-1] + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex constraint <%s>: %v", expr, err)
+		}
+		return &constraint{expr: expr, match: re.MatchString}, nil
+	}
+	constraintsMu.RLock()
+	match, ok := constraints[expr]
+	constraintsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown constraint <%s>", expr)
+	}
+	return &constraint{expr: expr, match: match}, nil
+}
+
+// 查找约束的结束位置 返回'>'的下标 regex中括号内的'>'和转义字符不计入
+func constraintEnd(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '>':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isInt(value string) bool {
+	if value != "" && value[0] == '-' {
+		value = value[1:]
+	}
+	return isUint(value)
+}
+
+func isUint(value string) bool {
+	if value == "" {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] < '0' || value[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(value string) bool {
+	if value == "" {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i] | 0x20
+		if c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+package router
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParamConstraint(t *testing.T) {
+	r := NewRouter()
+	r.AddRouter("GET", "/users/:id<int>", nil)
+	r.AddRouter("GET", "/users/:uuid<uuid>", nil)
+	r.AddRouter("GET", "/users/:name", nil)
+	r.AddRouter("GET", `/files/:name<regex(^[a-z]+\.txt$)>`, nil)
+	r.AddRouter("GET", "/codes/:code<regex([a-z]{2}/?)>/info", nil)
+
+	tests := []struct {
+		path    string
+		pattern string
+		key     string
+		value   string
+	}{
+		{"/users/42", "/users/:id<int>", "id", "42"},
+		{"/users/-1", "/users/:id<int>", "id", "-1"},
+		{"/users/1b4e28ba-2fa1-11d2-883f-0016d3cca427", "/users/:uuid<uuid>", "uuid", "1b4e28ba-2fa1-11d2-883f-0016d3cca427"},
+		{"/users/bob", "/users/:name", "name", "bob"},
+		{"/files/a.txt", `/files/:name<regex(^[a-z]+\.txt$)>`, "name", "a.txt"},
+		{"/files/a.csv", "", "", ""},
+		{"/files/A.txt", "", "", ""},
+		{"/codes/cn/info", "/codes/:code<regex([a-z]{2}/?)>/info", "code", "cn"},
+		// 正则需要完整匹配参数值
+		{"/codes/cnx/info", "", "", ""},
+	}
+	for _, test := range tests {
+		n, params := r.getRoute("GET", test.path)
+		if test.pattern == "" {
+			if n != nil {
+				t.Fatalf("%s should not match, got %s", test.path, n.pattern)
+			}
+			continue
+		}
+		if n == nil || n.pattern != test.pattern {
+			t.Fatalf("%s should match %s, got %v", test.path, test.pattern, n)
+		}
+		if params[test.key] != test.value {
+			t.Fatalf("%s: unexpected params %v", test.path, params)
+		}
+	}
+}
+
+func TestConstraintConflict(t *testing.T) {
+	r := NewRouter()
+	r.AddRouter("GET", "/users/:id<int>", nil)
+	r.AddRouter("GET", "/users/:id<int>/posts", nil)
+	invalid := []string{
+		"/users/:uid<int>",
+		"/users/:id<unknown>",
+		"/users/:id<int",
+		"/users/:id<int>x",
+		"/users/:id<regex(()>",
+		"/files/*path<int>",
+	}
+	for _, pattern := range invalid {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s should be rejected", pattern)
+				}
+			}()
+			r.AddRouter("GET", pattern, nil)
+		}()
+	}
+}
+
+func TestRegisterConstraint(t *testing.T) {
+	RegisterConstraint("lower", func(value string) bool {
+		return strings.ToLower(value) == value
+	})
+	r := NewRouter()
+	r.AddRouter("GET", "/tags/:tag<lower>", nil)
+	if n, _ := r.getRoute("GET", "/tags/go"); n == nil {
+		t.Fatal("/tags/go should match custom constraint")
+	}
+	if n, _ := r.getRoute("GET", "/tags/Go"); n != nil {
+		t.Fatal("/tags/Go should not match custom constraint")
+	}
+}
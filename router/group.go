@@ -8,21 +8,41 @@ import (
 // 分组路由
 type Group interface {
 	// HTTP 请求
-	Get(pattern string, handler context.Handler)
-	Post(pattern string, handler context.Handler)
-	Put(pattern string, handler context.Handler)
-	Patch(pattern string, handler context.Handler)
-	Head(pattern string, handler context.Handler)
-	Connect(pattern string, handler context.Handler)
-	Delete(pattern string, handler context.Handler)
-	Options(pattern string, handler context.Handler)
-	Trace(pattern string, handler context.Handler)
+	Get(pattern string, handlers ...context.Handler) *Route
+	Post(pattern string, handlers ...context.Handler) *Route
+	Put(pattern string, handlers ...context.Handler) *Route
+	Patch(pattern string, handlers ...context.Handler) *Route
+	Head(pattern string, handlers ...context.Handler) *Route
+	Connect(pattern string, handlers ...context.Handler) *Route
+	Delete(pattern string, handlers ...context.Handler) *Route
+	Options(pattern string, handlers ...context.Handler) *Route
+	Trace(pattern string, handlers ...context.Handler) *Route
 	// 添加路由信息
-	Handle(method string, pattern string, handler context.Handler)
+	Handle(method string, pattern string, handlers ...context.Handler) *Route
+	// 为所有标准请求方法添加路由
+	Any(pattern string, handlers ...context.Handler) []*Route
+	// 为指定的请求方法添加路由
+	Match(methods []string, pattern string, handlers ...context.Handler) []*Route
+	// 删除路由
+	RemoveRoute(method string, pattern string) bool
 	// 创建分组
 	Group(prefix string, handlers ...context.Handler) Group
+	// 创建按Host匹配的分组
+	Host(pattern string, handlers ...context.Handler) Group
+	// 挂载本地目录
+	Static(prefix string, dir string, options ...StaticOption) *Route
+	// 挂载文件系统
+	StaticFS(prefix string, fs http.FileSystem, options ...StaticOption) *Route
+	// 挂载单个文件
+	StaticFile(pattern string, file string) *Route
+	// 将前缀下的请求转发给handler
+	ReverseProxy(prefix string, handler http.Handler, options ...ProxyOption) Group
 	// 中间件注入
 	Use(handler ...context.Handler)
+	// 未匹配到路由时的处理
+	NoRoute(handlers ...context.Handler)
+	// 请求方法不匹配时的处理
+	NoMethod(handlers ...context.Handler)
 	// 获取group前缀
 	Prefix() string
 	// 获取Handler
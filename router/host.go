@@ -0,0 +1,155 @@
+package router
+
+import (
+	"fmt"
+	"github.com/yyxing/glu/context"
+	"strings"
+)
+
+// 按Host匹配的路由 每个Host有独立的路由表
+type hostRoute struct {
+	pattern string
+	// 按.拆分的域名 以:开头的为参数
+	labels  []string
+	wild    bool
+	builder *APIBuilder
+}
+
+// 创建按Host匹配的分组 支持 api.example.com 和 :tenant.example.com 两种写法
+// 捕获的参数可以通过Context.Param获取 精确匹配的Host优先于带参数的Host
+// 分组继承当前分组的前缀和中间件 规则中不能带端口
+func (api *APIBuilder) Host(pattern string, handlers ...context.Handler) Group {
+	main := api.router.main()
+	main.mu.Lock()
+	defer main.mu.Unlock()
+	pattern = strings.ToLower(pattern)
+	for _, h := range main.hosts {
+		if h.pattern == pattern {
+			return &APIBuilder{
+				parent:      api,
+				middlewares: joinHandlers(nil, handlers),
+				prefix:      api.prefix,
+				router:      h.builder.router,
+				host:        pattern,
+			}
+		}
+	}
+	labels, wild, err := parseHost(pattern)
+	if err != nil {
+		panic(err)
+	}
+	builder := &APIBuilder{
+		parent:      api,
+		middlewares: joinHandlers(nil, handlers),
+		prefix:      api.prefix,
+		router:      main.newHostRouter(),
+		host:        pattern,
+	}
+	for _, label := range labels {
+		if label[0] == paramPrefix {
+			builder.router.hostParams++
+		}
+	}
+	f := builder.router.getFallback(builder)
+	f.noRoute = context.Handlers{notFound}
+	f.noMethod = context.Handlers{methodNotAllowed}
+	builder.router.publishFallbacks()
+	h := &hostRoute{pattern: pattern, labels: labels, wild: wild, builder: builder}
+	// 精确匹配的Host排在带参数的Host之前
+	i := len(main.hosts)
+	if !wild {
+		for i = 0; i < len(main.hosts) && !main.hosts[i].wild; i++ {
+		}
+	}
+	// 复制后替换 不修改已发布的列表
+	hosts := make([]*hostRoute, 0, len(main.hosts)+1)
+	hosts = append(hosts, main.hosts[:i]...)
+	hosts = append(hosts, h)
+	main.hosts = append(hosts, main.hosts[i:]...)
+	t := main.load().clone()
+	t.hosts = main.hosts
+	main.current.Store(t)
+	return builder
+}
+
+func parseHost(pattern string) ([]string, bool, error) {
+	if pattern == "" {
+		return nil, false, fmt.Errorf("host pattern must not be empty")
+	}
+	labels := strings.Split(pattern, ".")
+	names := make(map[string]bool)
+	wild := false
+	for _, label := range labels {
+		if label == "" {
+			return nil, false, fmt.Errorf("host pattern '%s' has an empty label", pattern)
+		}
+		// 匹配时会去掉请求Host中的端口 带端口的规则永远不会匹配
+		if strings.IndexByte(label[1:], ':') >= 0 {
+			return nil, false, fmt.Errorf("host pattern '%s' must not contain a port", pattern)
+		}
+		if label[0] != paramPrefix {
+			continue
+		}
+		name := label[1:]
+		if name == "" || names[name] {
+			return nil, false, fmt.Errorf("host pattern '%s' has an invalid or duplicate param name", pattern)
+		}
+		names[name] = true
+		wild = true
+	}
+	return labels, wild, nil
+}
+
+// 匹配请求的Host 捕获的参数追加到params
+func (t *table) matchHost(host string, params *context.Params) *hostRoute {
+	host = strings.ToLower(stripHostPort(host))
+	for _, h := range t.hosts {
+		if !h.wild {
+			if h.pattern == host {
+				return h
+			}
+			continue
+		}
+		if h.match(host, params) {
+			return h
+		}
+	}
+	return nil
+}
+
+func (h *hostRoute) match(host string, params *context.Params) bool {
+	if strings.Count(host, ".")+1 != len(h.labels) {
+		return false
+	}
+	base := len(*params)
+	for _, label := range h.labels {
+		end := strings.IndexByte(host, '.')
+		if end < 0 {
+			end = len(host)
+		}
+		value := host[:end]
+		if label[0] == paramPrefix {
+			if value == "" {
+				*params = (*params)[:base]
+				return false
+			}
+			*params = append(*params, context.Param{Key: label[1:], Value: value})
+		} else if label != value {
+			*params = (*params)[:base]
+			return false
+		}
+		if end < len(host) {
+			host = host[end+1:]
+		}
+	}
+	return true
+}
+
+// 去掉Host中的端口 兼容IPv6地址
+func stripHostPort(host string) string {
+	i := strings.LastIndexByte(host, ':')
+	if i < 0 || strings.IndexByte(host[i:], ']') >= 0 {
+		return host
+	}
+	return host[:i]
+}
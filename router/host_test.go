@@ -0,0 +1,120 @@
+package router
+
+import (
+	"github.com/yyxing/glu/context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serveHost(api *APIBuilder, host string, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	request.Host = host
+	api.HandleRequest(w, request)
+	return w
+}
+
+func TestHostRouting(t *testing.T) {
+	api := NewAPIBuilder()
+	api.Get("/users/:id", write("default"))
+	api.Host("api.example.com").Get("/users/:id", write("api"))
+	tenant := api.Host(":tenant.example.com")
+	tenant.Get("/users/:id", func(c *context.Context) {
+		_, _ = c.WriteString(c.Param("tenant") + ":" + c.Param("id"))
+	})
+	api.Host(":tenant.:region.example.com").Get("/", func(c *context.Context) {
+		_, _ = c.WriteString(c.Param("tenant") + "@" + c.Param("region"))
+	})
+
+	tests := []struct {
+		host string
+		path string
+		body string
+		code int
+	}{
+		{"api.example.com", "/users/1", "api", http.StatusOK},
+		{"API.example.com:8080", "/users/1", "api", http.StatusOK},
+		{"acme.example.com", "/users/2", "acme:2", http.StatusOK},
+		{"acme.eu.example.com", "/", "acme@eu", http.StatusOK},
+		{"example.com", "/users/3", "default", http.StatusOK},
+		{"other.org", "/users/3", "default", http.StatusOK},
+		{"acme.example.com", "/missing", "404 NOT FOUND: /missing\n", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		w := serveHost(api, test.host, test.path)
+		if w.Code != test.code || w.Body.String() != test.body {
+			t.Fatalf("%s%s: unexpected response %d %q", test.host, test.path, w.Code, w.Body.String())
+		}
+	}
+
+	routes := api.Routes()
+	if len(routes) != 4 || routes[1].Host != "api.example.com" {
+		t.Fatalf("host routes should be listed: %v", routes)
+	}
+}
+
+func TestStripHostPort(t *testing.T) {
+	tests := map[string]string{
+		"example.com":    "example.com",
+		"example.com:80": "example.com",
+		"[::1]:8080":     "[::1]",
+		"[::1]":          "[::1]",
+		"127.0.0.1:8080": "127.0.0.1",
+	}
+	for host, expected := range tests {
+		if stripped := stripHostPort(host); stripped != expected {
+			t.Fatalf("stripHostPort(%q) = %q", host, stripped)
+		}
+	}
+}
+
+func TestHostUsesMainConfig(t *testing.T) {
+	api := NewAPIBuilder()
+	api.Host("api.example.com").Get("/users", write("users"))
+	if w := serveHost(api, "api.example.com", "/users/"); w.Code != http.StatusMovedPermanently {
+		t.Fatalf("expected redirect, got %d", w.Code)
+	}
+	// Host声明之后修改的配置同样生效
+	api.router.RedirectTrailingSlash = false
+	if w := serveHost(api, "api.example.com", "/users/"); w.Code != http.StatusNotFound {
+		t.Fatalf("redirect disabled should be 404, got %d", w.Code)
+	}
+}
+
+func TestHostRouteNames(t *testing.T) {
+	api := NewAPIBuilder()
+	api.Host("a.example.com").Get("/a", write("a")).SetName("page")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("route names should be unique across hosts")
+		}
+	}()
+	api.Host("b.example.com").Get("/b", write("b")).SetName("page")
+}
+
+func TestHostInheritsGroupPrefix(t *testing.T) {
+	api := NewAPIBuilder()
+	api.Group("/v1").Host("api.example.com").Get("/a", write("v1"))
+	api.Host("api.example.com").Get("/b", write("root"))
+	if w := serveHost(api, "api.example.com", "/v1/a"); w.Code != http.StatusOK || w.Body.String() != "v1" {
+		t.Fatalf("expected /v1/a to be served, got %d %q", w.Code, w.Body.String())
+	}
+	if w := serveHost(api, "api.example.com", "/b"); w.Code != http.StatusOK || w.Body.String() != "root" {
+		t.Fatalf("expected /b to be served, got %d %q", w.Code, w.Body.String())
+	}
+	if w := serveHost(api, "api.example.com", "/a"); w.Code != http.StatusNotFound {
+		t.Fatalf("expected /a to be 404, got %d", w.Code)
+	}
+}
+
+func TestHostPatternWithPort(t *testing.T) {
+	for _, pattern := range []string{"api.example.com:8080", ":tenant.example.com:80"} {
+		if _, _, err := parseHost(pattern); err == nil {
+			t.Fatalf("parseHost(%q) should reject the port", pattern)
+		}
+	}
+	if _, _, err := parseHost(":tenant.example.com"); err != nil {
+		t.Fatal(err)
+	}
+}
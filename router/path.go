@@ -0,0 +1,22 @@
+package router
+
+import (
+	"path"
+	"strings"
+)
+
+// 清理请求路径 去掉多余的/以及.和..路径段 保留末尾的/
+// //users/./1/../2/ => /users/2/
+func CleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	cleaned := path.Clean(p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
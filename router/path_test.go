@@ -0,0 +1,105 @@
+package router
+
+import (
+	"github.com/yyxing/glu/context"
+	"net/http"
+	"testing"
+)
+
+func TestCleanPath(t *testing.T) {
+	tests := map[string]string{
+		"":                "/",
+		"users":           "/users",
+		"//users//1":      "/users/1",
+		"/users/./1/../2": "/users/2",
+		"/users/../../x/": "/x/",
+		"/users/":         "/users/",
+		"/":               "/",
+	}
+	for path, expected := range tests {
+		if cleaned := CleanPath(path); cleaned != expected {
+			t.Fatalf("CleanPath(%q) = %q, expected %q", path, cleaned, expected)
+		}
+	}
+}
+
+func TestRedirectTrailingSlash(t *testing.T) {
+	api := NewAPIBuilder()
+	api.Get("/users", write("users"))
+	api.Post("/posts/", write("posts"))
+
+	w := serve(api, http.MethodGet, "/users/?page=1")
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/users?page=1" {
+		t.Fatalf("unexpected redirect: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	w = serve(api, http.MethodPost, "/posts")
+	if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != "/posts/" {
+		t.Fatalf("unexpected redirect: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	api.router.RedirectTrailingSlash = false
+	if w = serve(api, http.MethodGet, "/users/"); w.Code != http.StatusNotFound {
+		t.Fatalf("redirect disabled should be 404, got %d", w.Code)
+	}
+}
+
+func TestRedirectFixedPath(t *testing.T) {
+	api := NewAPIBuilder()
+	api.Get("/users/:name/Profile", write("profile"))
+	api.router.RedirectFixedPath = true
+
+	tests := map[string]string{
+		"/USERS/Bob/profile":          "/users/Bob/Profile",
+		"//users//Bob/./profile":      "/users/Bob/Profile",
+		"/users/x/../Bob/PROFILE/":    "/users/Bob/Profile",
+		"/users/a%20b/profile?tab=me": "/users/a%20b/Profile?tab=me",
+	}
+	for path, location := range tests {
+		w := serve(api, http.MethodGet, path)
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != location {
+			t.Fatalf("%s: unexpected redirect %d %q", path, w.Code, w.Header().Get("Location"))
+		}
+	}
+	// 不能重定向到其他站点
+	api.Get("/evil.com", write("evil"))
+	w := serve(api, http.MethodGet, "/evil.com/")
+	if w.Header().Get("Location") != "/evil.com" {
+		t.Fatalf("unexpected redirect %q", w.Header().Get("Location"))
+	}
+}
+
+func TestUseRawPath(t *testing.T) {
+	api := NewAPIBuilder()
+	var name string
+	api.Get("/files/:name/info", func(c *context.Context) {
+		name = c.Param("name")
+	})
+	if w := serve(api, http.MethodGet, "/files/a%2Fb/info"); w.Code != http.StatusNotFound {
+		t.Fatalf("encoded slash should split path without RawPath, got %d", w.Code)
+	}
+	api.router.UseRawPath = true
+	serve(api, http.MethodGet, "/files/a%2Fb/info")
+	if name != "a/b" {
+		t.Fatalf("param should be unescaped, got %q", name)
+	}
+	api.router.UnescapePathValues = false
+	serve(api, http.MethodGet, "/files/a%2Fb/info")
+	if name != "a%2Fb" {
+		t.Fatalf("param should keep escaped value, got %q", name)
+	}
+}
+
+func TestUseRawPathRedirect(t *testing.T) {
+	api := NewAPIBuilder()
+	api.Get("/files/:name/info", write("info"))
+	api.router.UseRawPath = true
+	// 请求没有RawPath时使用解码后的路径匹配 重定向地址需要重新编码
+	w := serve(api, http.MethodGet, "/files/a%3Fb%20c/info/")
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/files/a%3Fb%20c/info" {
+		t.Fatalf("unexpected redirect: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	// 使用RawPath匹配时保留原始编码
+	w = serve(api, http.MethodGet, "/files/a%2Fb/info/")
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/files/a%2Fb/info" {
+		t.Fatalf("unexpected redirect: %d %q", w.Code, w.Header().Get("Location"))
+	}
+}
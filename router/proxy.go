@@ -0,0 +1,82 @@
+package router
+
+import (
+	"github.com/yyxing/glu/context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// 代理挂载的配置
+type proxyMount struct {
+	prefix  string
+	handler http.Handler
+	// 转发前去掉挂载前缀
+	strip bool
+	// 转发前改写请求路径
+	rewrite func(path string) string
+}
+
+type ProxyOption func(*proxyMount)
+
+// 转发前去掉挂载前缀 /base/users转发为/users
+func ProxyStripPrefix() ProxyOption {
+	return func(m *proxyMount) {
+		m.strip = true
+	}
+}
+
+// 转发前改写请求路径 在去掉前缀之后执行
+func ProxyRewrite(rewrite func(path string) string) ProxyOption {
+	return func(m *proxyMount) {
+		m.rewrite = rewrite
+	}
+}
+
+// 将前缀下的所有请求转发给handler 按路径段匹配 与普通路由一样经过中间件
+func (api *APIBuilder) ReverseProxy(prefix string, handler http.Handler, options ...ProxyOption) Group {
+	prefix = jsonPrefixPath(api.prefix, prefix)
+	group := &APIBuilder{
+		parent:       api,
+		prefix:       prefix,
+		router:       api.router,
+		proxyHandler: handler,
+		host:         api.host,
+	}
+	mount := &proxyMount{prefix: prefix, handler: handler}
+	for _, option := range options {
+		option(mount)
+	}
+	group.Any("", mount.serve)
+	group.Any("/*proxyPath", mount.serve)
+	return group
+}
+
+func (m *proxyMount) serve(c *context.Context) {
+	if !m.strip && m.rewrite == nil {
+		m.handler.ServeHTTP(c.Writer, c.Request)
+		return
+	}
+	// 与http.StripPrefix一样复制请求 不修改原请求
+	r := new(http.Request)
+	*r = *c.Request
+	r.URL = new(url.URL)
+	*r.URL = *c.Request.URL
+	if m.strip {
+		r.URL.Path = stripPrefix(r.URL.Path, m.prefix)
+		if r.URL.RawPath != "" {
+			r.URL.RawPath = stripPrefix(r.URL.RawPath, m.prefix)
+		}
+	}
+	if m.rewrite != nil {
+		r.URL.Path = m.rewrite(r.URL.Path)
+		r.URL.RawPath = ""
+	}
+	r.RequestURI = r.URL.RequestURI()
+	m.handler.ServeHTTP(c.Writer, r)
+}
+
+// 去掉路径的挂载前缀 结果总是以/开头
+func stripPrefix(path string, prefix string) string {
+	return "/" + strings.TrimLeft(strings.TrimPrefix(path, prefix), "/")
+}
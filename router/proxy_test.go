@@ -0,0 +1,69 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// 记录转发到的路径
+func echoPath() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.Path))
+	})
+}
+
+func TestReverseProxyRoutes(t *testing.T) {
+	api := NewAPIBuilder()
+	var steps []string
+	api.Use(record(&steps, "global"))
+	api.ReverseProxy("/base", echoPath())
+	api.ReverseProxy("/strip", echoPath(), ProxyStripPrefix())
+	api.ReverseProxy("/rewrite", echoPath(), ProxyStripPrefix(), ProxyRewrite(func(path string) string {
+		return "/v2" + path
+	}))
+	api.Get("/baseball", write("baseball"))
+
+	cases := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodGet, "/base", "GET /base"},
+		{http.MethodPost, "/base/users/1", "POST /base/users/1"},
+		{http.MethodGet, "/baseball", "baseball"},
+		{http.MethodGet, "/strip", "GET /"},
+		{http.MethodDelete, "/strip/users/1", "DELETE /users/1"},
+		{http.MethodGet, "/rewrite/users", "GET /v2/users"},
+	}
+	for _, c := range cases {
+		steps = nil
+		w := serve(api, c.method, c.path)
+		if w.Body.String() != c.want {
+			t.Errorf("%s %s: got %q, want %q", c.method, c.path, w.Body.String(), c.want)
+		}
+		if strings.Join(steps, ",") != "global" {
+			t.Errorf("%s %s: middleware should run for proxied requests, got %v", c.method, c.path, steps)
+		}
+	}
+
+	if w := serve(api, http.MethodGet, "/basement"); w.Code != http.StatusNotFound {
+		t.Fatalf("proxy prefix should match by path segment, got %d", w.Code)
+	}
+}
+
+func TestReverseProxyGroupMiddleware(t *testing.T) {
+	api := NewAPIBuilder()
+	var steps []string
+	g := api.Group("/api", record(&steps, "auth"))
+	proxy := g.ReverseProxy("/users", echoPath(), ProxyStripPrefix())
+	proxy.Use(record(&steps, "proxy"))
+
+	w := serve(api, http.MethodGet, "/api/users/1")
+	if w.Body.String() != "GET /1" {
+		t.Fatalf("unexpected proxied path: %q", w.Body.String())
+	}
+	if got := strings.Join(steps, ","); got != "auth,proxy" {
+		t.Fatalf("got %s", got)
+	}
+}
@@ -0,0 +1,148 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"github.com/yyxing/glu/context"
+	"net/url"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+var (
+	ErrRouteNotFound = errors.New("route not found")
+)
+
+// 已注册的路由
+type Route struct {
+	// 路由名字 用于反向生成请求路径
+	Name   string
+	Method string
+	// 路由所属的Host 为空表示不限制Host
+	Host string
+	// 包含分组前缀的完整路径
+	Path string
+	// 完整的处理链 包括中间件
+	Handlers context.Handlers
+	// 处理链中中间件的数量
+	Middlewares int
+	// 注册路由的分组
+	group *APIBuilder
+	// 路由自身的handler 不包括中间件
+	handlers context.Handlers
+	router   *Router
+}
+
+// 根据分组当前的中间件重新生成处理链
+func (r *Route) resolve() {
+	if r.group == nil {
+		r.Handlers = joinHandlers(nil, r.handlers)
+		r.Middlewares = 0
+		return
+	}
+	chain := r.group.chain()
+	r.Handlers = joinHandlers(chain, r.handlers)
+	r.Middlewares = len(chain)
+}
+
+// 设置路由名字 名字重复时panic
+func (r *Route) SetName(name string) *Route {
+	if r.router != nil {
+		mu := r.router.locker()
+		mu.Lock()
+		defer mu.Unlock()
+		if exist := r.router.main().namedRoute(name); exist != nil && exist != r {
+			panic(fmt.Sprintf("route name %s already used by %s %s", name, exist.Method, exist.Path))
+		}
+	}
+	r.Name = name
+	return r
+}
+
+// 使用params依次填充路径中的:param和*wildcard参数并转义 参数需满足约束
+func (r *Route) URL(params ...interface{}) (string, error) {
+	segments, err := parsePattern(r.Path)
+	if err != nil {
+		return "", err
+	}
+	var (
+		builder strings.Builder
+		index   int
+	)
+	for _, seg := range segments {
+		if seg.typ == static {
+			builder.WriteString(seg.text)
+			continue
+		}
+		if index >= len(params) {
+			return "", fmt.Errorf("route %s: missing value for %s", r.Path, seg.text)
+		}
+		value := fmt.Sprint(params[index])
+		index++
+		if seg.typ == catchAll {
+			// 通配参数中的/需要保留
+			parts := strings.Split(strings.TrimPrefix(value, "/"), "/")
+			for i, part := range parts {
+				parts[i] = url.PathEscape(part)
+			}
+			builder.WriteString(strings.Join(parts, "/"))
+			continue
+		}
+		if value == "" {
+			return "", fmt.Errorf("route %s: empty value for %s", r.Path, seg.text)
+		}
+		if seg.constraint != nil && !seg.constraint.match(value) {
+			return "", fmt.Errorf("route %s: value %s does not satisfy %s%s", r.Path, value, seg.text, seg.constraint)
+		}
+		builder.WriteString(url.PathEscape(value))
+	}
+	if index != len(params) {
+		return "", fmt.Errorf("route %s: expected %d params, got %d", r.Path, index, len(params))
+	}
+	return builder.String(), nil
+}
+
+// 路由信息 用于展示服务对外提供的接口
+type RouteInfo struct {
+	Name   string
+	Method string
+	Host   string
+	Path   string
+	// 最终处理请求的handler名字
+	Handler string
+	// 处理链中每个handler的名字
+	Handlers    []string
+	Middlewares int
+}
+
+func (r *Route) Info() RouteInfo {
+	names := make([]string, len(r.Handlers))
+	for i, handler := range r.Handlers {
+		names[i] = handlerName(handler)
+	}
+	info := RouteInfo{
+		Name:        r.Name,
+		Method:      r.Method,
+		Host:        r.Host,
+		Path:        r.Path,
+		Handlers:    names,
+		Middlewares: r.Middlewares,
+	}
+	if len(names) > 0 {
+		info.Handler = names[len(names)-1]
+	}
+	return info
+}
+
+// 获取handler的函数名
+func handlerName(handler context.Handler) string {
+	if handler == nil {
+		return "<nil>"
+	}
+	fn := runtime.FuncForPC(reflect.ValueOf(handler).Pointer())
+	if fn == nil {
+		return "<unknown>"
+	}
+	return fn.Name()
+}
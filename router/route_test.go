@@ -0,0 +1,63 @@
+package router
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRouteURL(t *testing.T) {
+	api := NewAPIBuilder()
+	v1 := api.Group("/v1")
+	v1.Get("/users/:id/posts/:post", nil).SetName("post")
+	v1.Get("/files/*filepath", nil).SetName("file")
+
+	url, err := api.URL("post", 10, "hello world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "/v1/users/10/posts/hello%20world" {
+		t.Fatalf("unexpected url %s", url)
+	}
+	url, err = api.URL("file", "css/a b.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "/v1/files/css/a%20b.css" {
+		t.Fatalf("unexpected url %s", url)
+	}
+	if _, err = api.URL("post", 10); err == nil {
+		t.Fatal("missing params should return error")
+	}
+	if _, err = api.URL("post", 1, 2, 3); err == nil {
+		t.Fatal("extra params should return error")
+	}
+	if _, err = api.URL("missing"); !errors.Is(err, ErrRouteNotFound) {
+		t.Fatalf("unknown name should return ErrRouteNotFound, got %v", err)
+	}
+}
+
+func TestDuplicateRouteName(t *testing.T) {
+	api := NewAPIBuilder()
+	api.Get("/a", nil).SetName("a")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("duplicate route name should panic")
+		}
+	}()
+	api.Get("/b", nil).SetName("a")
+}
+
+func TestRouteURLConstraint(t *testing.T) {
+	api := NewAPIBuilder()
+	api.Get("/users/:id<int>/files/:name<regex([a-z/]+)>", nil).SetName("file")
+	url, err := api.URL("file", 1, "a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "/users/1/files/a%2Fb" {
+		t.Fatalf("unexpected url %s", url)
+	}
+	if _, err := api.URL("file", "x", "a"); err == nil {
+		t.Fatal("value violating constraint should return error")
+	}
+}
@@ -4,87 +4,579 @@ import (
 	"fmt"
 	"github.com/yyxing/glu/context"
 	"net/http"
+	"net/url"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
-var (
-	separator = "-"
-)
+// 路由表快照 发布后不再修改 处理请求时无锁读取
+// 注册和删除路由时复制修改的部分后整体替换
+type table struct {
+	// 每个请求方法一棵radix树
+	roots map[string]*node
+	// 路由中参数数量的最大值 用于预分配参数空间
+	maxParams int
+	// 已生成处理链的未匹配处理
+	fallbacks []*fallback
+	// 按Host匹配的路由表 仅在主路由表中有值
+	hosts []*hostRoute
+}
+
+func (t *table) clone() *table {
+	c := *t
+	return &c
+}
 
 type Router struct {
-	roots    map[string]*node
-	handlers map[string]context.Handlers
-}
-
-func parsePattern(pattern string) []string {
-	s := strings.Split(pattern, "/")
-	parts := make([]string, 0)
-	for _, part := range s {
-		if part != "" {
-			parts = append(parts, part)
-			if part[0] == '*' {
-				break
-			}
-		}
+	// 当前的路由表快照 *table
+	current atomic.Value
+	// 写操作的锁 Host路由表使用主路由表的锁
+	mu sync.Mutex
+	// 以下字段只在持有锁时访问
+	// 按注册顺序记录的路由
+	routes []*Route
+	// 按分组前缀注册的未匹配处理
+	fallbacks []*fallback
+	// 按Host匹配的路由表 仅在主路由表中有值
+	hosts []*hostRoute
+	// Host路由表所属的主路由表
+	parent *Router
+	// Host路由表对应Host中的参数数量
+	hostParams int
+	// 以下配置以主路由表为准 Host路由表处理请求时读取主路由表的当前配置
+	// 路径存在但请求方法不匹配时返回405 否则按404处理
+	HandleMethodNotAllowed bool
+	// 未注册HEAD时使用GET的处理并丢弃响应body
+	HandleHEAD bool
+	// 未注册OPTIONS时自动响应 Allow中包含该路径已注册的方法
+	HandleOPTIONS bool
+	// 路径不匹配但增加或去掉末尾的/后匹配时重定向 GET请求返回301 其他返回308
+	RedirectTrailingSlash bool
+	// 路径不匹配时清理多余的/和./../并忽略大小写重新查找 匹配则重定向
+	RedirectFixedPath bool
+	// 使用URL.RawPath匹配路由 参数中编码过的/不会被当作路径分隔符
+	UseRawPath bool
+	// 使用RawPath匹配时对参数值解码
+	UnescapePathValues bool
+}
+
+// 分组的NoRoute/NoMethod处理 执行时会先经过分组的中间件
+type fallback struct {
+	prefix   string
+	group    *APIBuilder
+	noRoute  context.Handlers
+	noMethod context.Handlers
+	// 以下为加上分组中间件后的处理链
+	middlewares   context.Handlers
+	noRouteChain  context.Handlers
+	noMethodChain context.Handlers
+}
+
+// 根据分组当前的中间件生成处理链
+func (f *fallback) resolve() {
+	f.middlewares = f.group.chain()
+	f.noRouteChain = nil
+	if f.noRoute != nil {
+		f.noRouteChain = joinHandlers(f.middlewares, f.noRoute)
+	}
+	f.noMethodChain = nil
+	if f.noMethod != nil {
+		f.noMethodChain = joinHandlers(f.middlewares, f.noMethod)
 	}
-	return parts
 }
+
 func (router *Router) AddRouter(method string, pattern string, handler ...context.Handler) {
-	key := method + separator + pattern
-	_, ok := router.roots[method]
-	if !ok {
-		router.roots[method] = &node{children: make([]*node, 0)}
+	router.addRoute(&Route{Method: method, Path: pattern, handlers: handler, router: router})
+}
+
+// 获取当前的路由表快照
+func (router *Router) load() *table {
+	return router.current.Load().(*table)
+}
+
+// 获取写操作的锁
+func (router *Router) locker() *sync.Mutex {
+	return &router.main().mu
+}
+
+// 注册路由 路由模式不合法或与已有路由冲突时panic
+// 只复制插入路径上的节点 正在处理的请求继续使用旧的路由表
+func (router *Router) addRoute(route *Route) {
+	mu := router.locker()
+	mu.Lock()
+	defer mu.Unlock()
+	route.resolve()
+	t := router.load().clone()
+	t.roots = make(map[string]*node, len(t.roots)+1)
+	for method, root := range router.load().roots {
+		t.roots[method] = root
 	}
-	parts := parsePattern(pattern)
-	err := router.roots[method].insert(pattern, parts, 0)
+	root, ok := t.roots[route.Method]
+	if ok {
+		root = root.clone()
+	} else {
+		root = &node{}
+	}
+	leaf, err := root.insert(route.Path, route)
 	if err != nil {
 		panic(err)
 	}
-	router.handlers[key] = append(router.handlers[key], handler...)
+	t.roots[route.Method] = root
+	router.routes = append(router.routes, route)
+	router.current.Store(t)
+	// Host路由的参数包括Host中的参数 主路由表记录所有路由表中的最大值
+	n := len(leaf.paramNames) + router.hostParams
+	for r := router; r != nil; r = r.parent {
+		if t := r.load(); n > t.maxParams {
+			t = t.clone()
+			t.maxParams = n
+			r.current.Store(t)
+		}
+	}
 }
 
-// 查找路由
-func (router *Router) getRoute(method string, path string) (*node, map[string]string) {
-	searchParts := parsePattern(path)
-	params := make(map[string]string)
-	root, ok := router.roots[method]
+// 删除路由 path为包含分组前缀的完整路径 路由不存在时返回false
+// 正在处理的请求不受影响
+func (router *Router) RemoveRoute(method string, path string) bool {
+	mu := router.locker()
+	mu.Lock()
+	defer mu.Unlock()
+	for i, route := range router.routes {
+		if route.Method != method || route.Path != path {
+			continue
+		}
+		routes := make([]*Route, 0, len(router.routes)-1)
+		routes = append(routes, router.routes[:i]...)
+		router.routes = append(routes, router.routes[i+1:]...)
+		router.rebuild()
+		return true
+	}
+	return false
+}
+
+// 根据已注册的路由重新生成路由表 调用方需持有锁
+func (router *Router) rebuild() {
+	t := &table{roots: make(map[string]*node), hosts: router.hosts}
+	for _, route := range router.routes {
+		route.resolve()
+		root, ok := t.roots[route.Method]
+		if !ok {
+			root = &node{}
+			t.roots[route.Method] = root
+		}
+		leaf, err := root.insert(route.Path, route)
+		if err != nil {
+			panic(err)
+		}
+		if n := len(leaf.paramNames) + router.hostParams; n > t.maxParams {
+			t.maxParams = n
+		}
+	}
+	for _, h := range router.hosts {
+		if n := h.builder.router.load().maxParams; n > t.maxParams {
+			t.maxParams = n
+		}
+	}
+	t.fallbacks = router.resolveFallbacks()
+	router.current.Store(t)
+}
+
+func (router *Router) routeByName(name string) *Route {
+	for _, route := range router.routes {
+		if route.Name == name {
+			return route
+		}
+	}
+	return nil
+}
+
+// 在主路由表和所有Host路由表中按名字查找路由 调用方需持有锁
+func (router *Router) namedRoute(name string) *Route {
+	route := router.routeByName(name)
+	for i := 0; route == nil && i < len(router.hosts); i++ {
+		route = router.hosts[i].builder.router.routeByName(name)
+	}
+	return route
+}
+
+// 根据路由名字生成请求路径 params依次填充路径中的参数
+func (router *Router) URL(name string, params ...interface{}) (string, error) {
+	mu := router.locker()
+	mu.Lock()
+	defer mu.Unlock()
+	route := router.main().namedRoute(name)
+	if route == nil {
+		return "", fmt.Errorf("%w: %s", ErrRouteNotFound, name)
+	}
+	return route.URL(params...)
+}
+
+// 按注册顺序返回所有路由的信息
+func (router *Router) Routes() []RouteInfo {
+	mu := router.locker()
+	mu.Lock()
+	defer mu.Unlock()
+	return router.routeInfos()
+}
+
+func (router *Router) routeInfos() []RouteInfo {
+	infos := make([]RouteInfo, 0, len(router.routes))
+	for _, route := range router.routes {
+		infos = append(infos, route.Info())
+	}
+	for _, h := range router.hosts {
+		infos = append(infos, h.builder.router.routeInfos()...)
+	}
+	return infos
+}
+
+// 查找路由 参数值按顺序追加到values
+func (t *table) find(method string, path string, values *context.Params) *node {
+	root, ok := t.roots[method]
 	if !ok {
-		return nil, nil
+		return nil
 	}
-	node := root.search(path, searchParts, 0)
+	return root.search(path, values)
+}
+
+// 查找路由并返回参数
+func (router *Router) getRoute(method string, path string) (*node, map[string]string) {
+	t := router.load()
+	values := make(context.Params, 0, t.maxParams)
+	node := t.find(method, path, &values)
 	if node == nil {
 		return nil, nil
 	}
-	// 表示找到了path对应的handler
-	// 将参数抽取出来
-	parts := parsePattern(node.pattern)
-	for i, part := range parts {
-		_, paramName := pathValid(part)
-		if paramName != "" {
-			if part[0] == '*' && len(part) > 1 {
-				params[paramName] = strings.Join(searchParts[i:], "/")
-				break
-			}
-			params[paramName] = searchParts[i]
+	var params map[string]string
+	if len(node.paramNames) > 0 {
+		params = make(map[string]string, len(node.paramNames))
+		for i, name := range node.paramNames {
+			params[name] = values[i].Value
 		}
 	}
 	return node, params
 }
 
+// 查找请求方法和路径对应的处理链 参数追加到ctx.Params unescape为true时对参数值进行解码
+func (t *table) lookup(ctx *context.Context, method string, path string, unescape bool) (context.Handlers, bool) {
+	base := len(ctx.Params)
+	node := t.find(method, path, &ctx.Params)
+	if node == nil {
+		ctx.Params = ctx.Params[:base]
+		return nil, false
+	}
+	for i, name := range node.paramNames {
+		param := &ctx.Params[base+i]
+		param.Key = name
+		if unescape {
+			if v, err := url.PathUnescape(param.Value); err == nil {
+				param.Value = v
+			}
+		}
+	}
+	return node.handlers, true
+}
+
+// 获取用于匹配的请求路径 返回的路径是否为编码过的RawPath
+func (router *Router) requestPath(ctx *context.Context) (string, bool) {
+	if router.main().UseRawPath && ctx.Request.URL.RawPath != "" {
+		return ctx.Request.URL.RawPath, true
+	}
+	return ctx.Path, false
+}
+
 func (router *Router) Serve(ctx *context.Context) {
-	node, params := router.getRoute(ctx.Method, ctx.Path)
-	if node != nil {
-		ctx.Params = params
-		key := ctx.Method + separator + node.pattern
-		mainHandler := router.handlers[key]
-		ctx.SetHandlers(mainHandler...)
+	t := router.load()
+	path, raw := router.requestPath(ctx)
+	// 使用RawPath匹配时参数值需要解码
+	unescape := raw && router.main().UnescapePathValues
+	if handlers, ok := t.lookup(ctx, ctx.Method, path, unescape); ok {
+		ctx.SetHandlers(handlers...)
+	} else if handlers, ok := router.autoHead(t, ctx, path, unescape); ok {
+		// net/http会丢弃HEAD请求的响应body 响应头与GET一致
+		ctx.SetHandlers(handlers...)
+	} else if fixed, ok := router.fixPath(t, ctx.Method, path); ok {
+		ctx.SetHandlers(joinHandlers(t.routeMiddlewares(fixed, ctx.Method, http.MethodGet), context.Handlers{redirect(ctx, fixed, !raw)})...)
+	} else if allow := router.allowed(t, path); allow != "" && ctx.Method == http.MethodOptions && router.main().HandleOPTIONS {
+		ctx.Header("Allow", allow)
+		ctx.SetHandlers(joinHandlers(t.routeMiddlewares(path, http.MethodGet), context.Handlers{options})...)
+	} else if allow != "" && router.main().HandleMethodNotAllowed {
+		ctx.Header("Allow", allow)
+		ctx.SetHandlers(t.noMethodHandlers(path)...)
 	} else {
-		ctx.StatusCode(http.StatusNotFound)
-		_, _ = ctx.WriteString(fmt.Sprintf("404 NOT FOUND: %s\n", ctx.Path))
+		ctx.SetHandlers(t.noRouteHandlers(path)...)
 	}
 	// 开始触发Handler
 	ctx.Next()
 }
+
+// 判断路由是否存在 包括自动响应的HEAD
+func (router *Router) exists(t *table, method string, path string) bool {
+	values := make(context.Params, 0, t.maxParams)
+	if t.find(method, path, &values) != nil {
+		return true
+	}
+	return method == http.MethodHead && router.main().HandleHEAD && t.find(http.MethodGet, path, &values) != nil
+}
+
+// 尝试修正请求路径 返回修正后存在路由的路径
+func (router *Router) fixPath(t *table, method string, path string) (string, bool) {
+	if method == http.MethodConnect || path == "/" {
+		return "", false
+	}
+	if router.main().RedirectTrailingSlash {
+		if alt := toggleTrailingSlash(path); router.exists(t, method, alt) {
+			return alt, true
+		}
+	}
+	if router.main().RedirectFixedPath {
+		cleaned := CleanPath(path)
+		if fixed, ok := router.findCaseInsensitive(t, method, cleaned); ok {
+			return fixed, true
+		}
+		if router.main().RedirectTrailingSlash {
+			if fixed, ok := router.findCaseInsensitive(t, method, toggleTrailingSlash(cleaned)); ok {
+				return fixed, true
+			}
+		}
+	}
+	return "", false
+}
+
+// 忽略大小写查找路由 返回注册时的路径写法 参数值保持不变
+func (router *Router) findCaseInsensitive(t *table, method string, path string) (string, bool) {
+	if root, ok := t.roots[method]; ok {
+		if fixed, ok := root.searchCaseInsensitive(path, make([]byte, 0, len(path))); ok {
+			return string(fixed), true
+		}
+	}
+	if method == http.MethodHead && router.main().HandleHEAD {
+		return router.findCaseInsensitive(t, http.MethodGet, path)
+	}
+	return "", false
+}
+
+func toggleTrailingSlash(path string) string {
+	if strings.HasSuffix(path, "/") {
+		return path[:len(path)-1]
+	}
+	return path + "/"
+}
+
+// HEAD请求未注册时使用GET的处理链
+func (router *Router) autoHead(t *table, ctx *context.Context, path string, unescape bool) (context.Handlers, bool) {
+	if ctx.Method != http.MethodHead || !router.main().HandleHEAD {
+		return nil, false
+	}
+	return t.lookup(ctx, http.MethodGet, path, unescape)
+}
+
+// 获取path已注册的方法列表 包括自动响应的HEAD和OPTIONS 用于Allow响应头
+func (router *Router) allowed(t *table, path string) string {
+	methods := make([]string, 0)
+	values := make(context.Params, 0, t.maxParams)
+	for method := range t.roots {
+		if node := t.find(method, path, &values); node != nil {
+			methods = append(methods, method)
+		}
+		values = values[:0]
+	}
+	if len(methods) == 0 {
+		return ""
+	}
+	if router.main().HandleHEAD && containsMethod(methods, http.MethodGet) && !containsMethod(methods, http.MethodHead) {
+		methods = append(methods, http.MethodHead)
+	}
+	if router.main().HandleOPTIONS && !containsMethod(methods, http.MethodOptions) {
+		methods = append(methods, http.MethodOptions)
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// 注册前缀下的NoRoute处理
+func (router *Router) setNoRoute(group *APIBuilder, handlers context.Handlers) {
+	mu := router.locker()
+	mu.Lock()
+	defer mu.Unlock()
+	router.getFallback(group).noRoute = handlers
+	router.publishFallbacks()
+}
+
+// 注册前缀下的NoMethod处理
+func (router *Router) setNoMethod(group *APIBuilder, handlers context.Handlers) {
+	mu := router.locker()
+	mu.Lock()
+	defer mu.Unlock()
+	router.getFallback(group).noMethod = handlers
+	router.publishFallbacks()
+}
+
+// 复制未匹配处理并生成处理链 调用方需持有锁
+func (router *Router) resolveFallbacks() []*fallback {
+	fallbacks := make([]*fallback, 0, len(router.fallbacks))
+	for _, f := range router.fallbacks {
+		c := *f
+		c.resolve()
+		fallbacks = append(fallbacks, &c)
+	}
+	return fallbacks
+}
+
+// 发布新的未匹配处理 调用方需持有锁
+func (router *Router) publishFallbacks() {
+	t := router.load().clone()
+	t.fallbacks = router.resolveFallbacks()
+	router.current.Store(t)
+}
+
+// 每个分组有独立的未匹配处理 前缀相同的分组不共享中间件
+func (router *Router) getFallback(group *APIBuilder) *fallback {
+	for _, f := range router.fallbacks {
+		if f.group == group {
+			return f
+		}
+	}
+	f := &fallback{prefix: group.prefix, group: group}
+	router.fallbacks = append(router.fallbacks, f)
+	return f
+}
+
+// 按路径段匹配前缀最长且设置了对应处理的分组 前缀相同时后设置的分组优先
+func (t *table) matchFallback(path string, has func(f *fallback) bool) *fallback {
+	var matched *fallback
+	for _, f := range t.fallbacks {
+		if !has(f) || !hasPathPrefix(path, f.prefix) {
+			continue
+		}
+		if matched == nil || len(f.prefix) >= len(matched.prefix) {
+			matched = f
+		}
+	}
+	return matched
+}
+
+// path上已注册路由所在分组的中间件 依次查找methods和按字母排序的其他方法
+// 用于自动响应的OPTIONS和重定向 与该路径的路由经过相同的中间件
+func (t *table) routeMiddlewares(path string, methods ...string) context.Handlers {
+	others := make([]string, 0, len(t.roots))
+	for method := range t.roots {
+		others = append(others, method)
+	}
+	sort.Strings(others)
+	values := make(context.Params, 0, t.maxParams)
+	for _, method := range append(methods, others...) {
+		values = values[:0]
+		if n := t.find(method, path, &values); n != nil {
+			return n.middlewares
+		}
+	}
+	return nil
+}
+
+func (t *table) noRouteHandlers(path string) context.Handlers {
+	f := t.matchFallback(path, func(f *fallback) bool { return f.noRoute != nil })
+	if f == nil {
+		return context.Handlers{notFound}
+	}
+	return f.noRouteChain
+}
+
+func (t *table) noMethodHandlers(path string) context.Handlers {
+	f := t.matchFallback(path, func(f *fallback) bool { return f.noMethod != nil })
+	if f == nil {
+		return context.Handlers{methodNotAllowed}
+	}
+	return f.noMethodChain
+}
+
+// 按路径段判断前缀 /base 不匹配 /baseball
+func hasPathPrefix(path string, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || path[len(prefix)] == '/'
+}
+
+func notFound(ctx *context.Context) {
+	ctx.StatusCode(http.StatusNotFound)
+	_, _ = ctx.WriteString(fmt.Sprintf("404 NOT FOUND: %s\n", ctx.Path))
+}
+
+func methodNotAllowed(ctx *context.Context) {
+	ctx.StatusCode(http.StatusMethodNotAllowed)
+	_, _ = ctx.WriteString(fmt.Sprintf("405 METHOD NOT ALLOWED: %s\n", ctx.Path))
+}
+
+// 自动响应OPTIONS Allow响应头已设置
+func options(ctx *context.Context) {
+	ctx.StatusCode(http.StatusNoContent)
+}
+
+// 重定向到修正后的路径 保留查询参数
+func redirect(ctx *context.Context, path string, escape bool) context.Handler {
+	code := http.StatusPermanentRedirect
+	if ctx.Method == http.MethodGet || ctx.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	location := path
+	if escape {
+		location = (&url.URL{Path: path}).EscapedPath()
+	}
+	// 避免被当作协议相对地址重定向到其他站点
+	location = "/" + strings.TrimLeft(location, "/")
+	if query := ctx.Request.URL.RawQuery; query != "" {
+		location += "?" + query
+	}
+	return func(ctx *context.Context) {
+		ctx.Header("Location", location)
+		ctx.StatusCode(code)
+	}
+}
+
+// 获取主路由表
+func (router *Router) main() *Router {
+	for router.parent != nil {
+		router = router.parent
+	}
+	return router
+}
+
+// 中间件变化后重新生成所有路由表 包括Host路由表 调用方需持有锁
+func (router *Router) refresh() {
+	for _, h := range router.hosts {
+		h.builder.router.refresh()
+	}
+	router.rebuild()
+}
+
+// 创建Host路由表 处理请求时使用主路由表的配置
+func (router *Router) newHostRouter() *Router {
+	r := NewRouter()
+	r.parent = router
+	return r
+}
+
 func NewRouter() *Router {
-	return &Router{handlers: make(map[string]context.Handlers), roots: make(map[string]*node)}
+	router := &Router{
+		HandleMethodNotAllowed: true,
+		HandleHEAD:             true,
+		HandleOPTIONS:          true,
+		RedirectTrailingSlash:  true,
+		UnescapePathValues:     true,
+	}
+	router.current.Store(&table{roots: make(map[string]*node)})
+	return router
 }
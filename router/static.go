@@ -0,0 +1,200 @@
+package router
+
+import (
+	"fmt"
+	"github.com/yyxing/glu/context"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 默认的目录首页
+const defaultIndex = "index.html"
+
+// 静态文件服务的配置
+type staticServer struct {
+	fs http.FileSystem
+	// 目录首页文件名
+	index string
+	// 目录没有首页时是否列出文件
+	browse bool
+	// 文件不存在时返回根目录首页 用于单页应用
+	spa bool
+}
+
+type StaticOption func(*staticServer)
+
+// 目录没有首页时列出目录下的文件 默认返回404
+func StaticBrowse() StaticOption {
+	return func(s *staticServer) {
+		s.browse = true
+	}
+}
+
+// 设置目录首页文件名 默认index.html 为空时不使用首页
+func StaticIndex(name string) StaticOption {
+	return func(s *staticServer) {
+		s.index = name
+	}
+}
+
+// 文件不存在时返回根目录首页 前端路由由单页应用处理
+func StaticSPA() StaticOption {
+	return func(s *staticServer) {
+		s.spa = true
+	}
+}
+
+// 将本地目录挂载到prefix下
+func (api *APIBuilder) Static(prefix string, dir string, options ...StaticOption) *Route {
+	return api.StaticFS(prefix, http.Dir(dir), options...)
+}
+
+// 将文件系统挂载到prefix下 文件路径由通配参数filepath匹配
+// 嵌入的资源可以通过实现http.FileSystem挂载
+func (api *APIBuilder) StaticFS(prefix string, fs http.FileSystem, options ...StaticOption) *Route {
+	s := &staticServer{fs: fs, index: defaultIndex}
+	for _, option := range options {
+		option(s)
+	}
+	return api.Get(strings.TrimRight(prefix, "/")+"/*filepath", s.serve)
+}
+
+// 将单个本地文件挂载到pattern
+func (api *APIBuilder) StaticFile(pattern string, file string) *Route {
+	dir, name := filepath.Split(file)
+	fs := http.Dir(dir)
+	return api.Get(pattern, func(c *context.Context) {
+		f, err := fs.Open("/" + name)
+		if err != nil {
+			notFound(c)
+			return
+		}
+		defer f.Close()
+		d, err := f.Stat()
+		if err != nil || d.IsDir() {
+			notFound(c)
+			return
+		}
+		serveContent(c, d, f)
+	})
+}
+
+func (s *staticServer) serve(c *context.Context) {
+	name := c.Param("filepath")
+	if !validFilePath(name) {
+		c.StatusCode(http.StatusBadRequest)
+		_, _ = c.WriteString(fmt.Sprintf("400 BAD REQUEST: %s\n", c.Path))
+		return
+	}
+	name = path.Clean("/" + name)
+	f, err := s.fs.Open(name)
+	if err != nil {
+		if s.spa && os.IsNotExist(err) {
+			s.serveIndex(c)
+			return
+		}
+		notFound(c)
+		return
+	}
+	defer f.Close()
+	d, err := f.Stat()
+	if err != nil {
+		notFound(c)
+		return
+	}
+	if !d.IsDir() {
+		serveContent(c, d, f)
+		return
+	}
+	// 目录地址统一以/结尾 保证页面中的相对路径正确
+	if !strings.HasSuffix(c.Request.URL.Path, "/") {
+		redirect(c, c.Request.URL.Path+"/", true)(c)
+		return
+	}
+	if s.index != "" {
+		if index, err := s.fs.Open(path.Join(name, s.index)); err == nil {
+			defer index.Close()
+			if id, err := index.Stat(); err == nil && !id.IsDir() {
+				serveContent(c, id, index)
+				return
+			}
+		}
+	}
+	switch {
+	case s.browse:
+		listDir(c, f)
+	case s.spa:
+		s.serveIndex(c)
+	default:
+		notFound(c)
+	}
+}
+
+// 返回根目录首页
+func (s *staticServer) serveIndex(c *context.Context) {
+	if s.index == "" {
+		notFound(c)
+		return
+	}
+	f, err := s.fs.Open("/" + s.index)
+	if err != nil {
+		notFound(c)
+		return
+	}
+	defer f.Close()
+	d, err := f.Stat()
+	if err != nil || d.IsDir() {
+		notFound(c)
+		return
+	}
+	serveContent(c, d, f)
+}
+
+// 设置ETag后交给http.ServeContent 处理Last-Modified、条件请求和Range
+func serveContent(c *context.Context, d os.FileInfo, f http.File) {
+	c.Header("ETag", fmt.Sprintf(`W/"%x-%x"`, d.ModTime().UnixNano(), d.Size()))
+	http.ServeContent(c.Writer, c.Request, d.Name(), d.ModTime(), f)
+}
+
+// 列出目录下的文件
+func listDir(c *context.Context, f http.File) {
+	files, err := f.Readdir(-1)
+	if err != nil {
+		c.StatusCode(http.StatusInternalServerError)
+		_, _ = c.WriteString("500 INTERNAL SERVER ERROR: cannot read directory\n")
+		return
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+	c.ContentType("text/html; charset=utf-8")
+	var b strings.Builder
+	b.WriteString("<pre>\n")
+	for _, d := range files {
+		name := d.Name()
+		if d.IsDir() {
+			name += "/"
+		}
+		href := (&url.URL{Path: name}).String()
+		fmt.Fprintf(&b, "<a href=\"%s\">%s</a>\n", html.EscapeString(href), html.EscapeString(name))
+	}
+	b.WriteString("</pre>\n")
+	_, _ = c.WriteString(b.String())
+}
+
+// 文件路径不能包含..路径段、反斜杠和空字符 防止访问根目录之外的文件
+func validFilePath(name string) bool {
+	if strings.ContainsAny(name, "\\\x00") {
+		return false
+	}
+	for _, seg := range strings.Split(name, "/") {
+		if seg == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+package router
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func staticDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "glu-static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"index.html":      "home",
+		"app.js":          "console.log(1)",
+		"docs/guide.txt":  "0123456789",
+		"empty/.keep":     "",
+		"docs/index.html": "docs home",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestStatic(t *testing.T) {
+	dir := staticDir(t)
+	defer os.RemoveAll(dir)
+	// 根目录之外的文件
+	secret := filepath.Join(filepath.Dir(dir), "glu-secret.txt")
+	_ = ioutil.WriteFile(secret, []byte("secret"), 0644)
+	defer os.Remove(secret)
+
+	api := NewAPIBuilder()
+	api.Static("/assets", dir)
+	api.Static("/browse", dir, StaticBrowse(), StaticIndex(""))
+
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/assets/app.js", http.StatusOK, "console.log(1)"},
+		{"/assets/", http.StatusOK, "home"},
+		{"/assets/docs/", http.StatusOK, "docs home"},
+		{"/assets/empty/", http.StatusNotFound, ""},
+		{"/assets/missing.js", http.StatusNotFound, ""},
+		{"/assets/../glu-secret.txt", http.StatusBadRequest, ""},
+		{"/assets/docs/../../glu-secret.txt", http.StatusBadRequest, ""},
+		{"/assets/%2e%2e/glu-secret.txt", http.StatusBadRequest, ""},
+	}
+	for _, c := range cases {
+		w := serve(api, http.MethodGet, c.path)
+		if w.Code != c.code || (c.body != "" && w.Body.String() != c.body) {
+			t.Errorf("%s: got %d %q", c.path, w.Code, w.Body.String())
+		}
+		if w.Body.String() == "secret" {
+			t.Fatalf("%s: file outside root served", c.path)
+		}
+	}
+
+	w := serve(api, http.MethodGet, "/assets/docs")
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/assets/docs/" {
+		t.Fatalf("directory without slash should redirect: %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	w = serve(api, http.MethodGet, "/browse/")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `<a href="docs/">docs/</a>`) ||
+		!strings.Contains(w.Body.String(), `<a href="app.js">app.js</a>`) {
+		t.Fatalf("unexpected listing: %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestStaticCaching(t *testing.T) {
+	dir := staticDir(t)
+	defer os.RemoveAll(dir)
+	api := NewAPIBuilder()
+	api.StaticFS("/", http.Dir(dir))
+
+	w := serve(api, http.MethodGet, "/docs/guide.txt")
+	etag, modified := w.Header().Get("ETag"), w.Header().Get("Last-Modified")
+	if etag == "" || modified == "" {
+		t.Fatalf("missing validators: %q %q", etag, modified)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/docs/guide.txt", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	api.HandleRequest(w, r)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("If-None-Match: got %d", w.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/docs/guide.txt", nil)
+	r.Header.Set("If-Modified-Since", modified)
+	w = httptest.NewRecorder()
+	api.HandleRequest(w, r)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("If-Modified-Since: got %d", w.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/docs/guide.txt", nil)
+	r.Header.Set("Range", "bytes=2-5")
+	w = httptest.NewRecorder()
+	api.HandleRequest(w, r)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "2345" {
+		t.Fatalf("Range: got %d %q", w.Code, w.Body.String())
+	}
+
+	w = serve(api, http.MethodHead, "/app.js")
+	if w.Code != http.StatusOK || w.Body.Len() != 0 || w.Header().Get("Content-Length") != "14" {
+		t.Fatalf("HEAD: got %d %q %q", w.Code, w.Body.String(), w.Header().Get("Content-Length"))
+	}
+}
+
+func TestStaticSPAAndFile(t *testing.T) {
+	dir := staticDir(t)
+	defer os.RemoveAll(dir)
+	api := NewAPIBuilder()
+	api.Static("/app", dir, StaticSPA())
+	api.StaticFile("/favicon.js", filepath.Join(dir, "app.js"))
+
+	for _, p := range []string{"/app/users/1", "/app/empty/"} {
+		if w := serve(api, http.MethodGet, p); w.Code != http.StatusOK || w.Body.String() != "home" {
+			t.Fatalf("%s: SPA fallback got %d %q", p, w.Code, w.Body.String())
+		}
+	}
+	if w := serve(api, http.MethodGet, "/app/app.js"); w.Body.String() != "console.log(1)" {
+		t.Fatalf("existing file should be served: %q", w.Body.String())
+	}
+	if w := serve(api, http.MethodGet, "/favicon.js"); w.Code != http.StatusOK || w.Body.String() != "console.log(1)" {
+		t.Fatalf("StaticFile: got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,106 @@
+package router
+
+import (
+	"github.com/yyxing/glu/context"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRemoveRoute(t *testing.T) {
+	api := NewAPIBuilder()
+	v1 := api.Group("/v1")
+	v1.Get("/users/:id", write("user"))
+	v1.Get("/users/:id/posts", write("posts"))
+	v1.Post("/users/:id", write("update"))
+
+	if !v1.RemoveRoute(http.MethodGet, "/users/:id") {
+		t.Fatal("route should be removed")
+	}
+	if v1.RemoveRoute(http.MethodGet, "/users/:id") {
+		t.Fatal("removed route should not be found again")
+	}
+	if w := serve(api, http.MethodGet, "/v1/users/1"); w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("removed route still served: %d %q", w.Code, w.Body.String())
+	}
+	if w := serve(api, http.MethodGet, "/v1/users/1/posts"); w.Body.String() != "posts" {
+		t.Fatalf("other routes should be kept: %q", w.Body.String())
+	}
+	for _, info := range api.Routes() {
+		if info.Method == http.MethodGet && info.Path == "/v1/users/:id" {
+			t.Fatal("removed route still listed")
+		}
+	}
+	// 删除后可以重新注册
+	v1.Get("/users/:id", write("user again"))
+	if w := serve(api, http.MethodGet, "/v1/users/1"); w.Body.String() != "user again" {
+		t.Fatalf("re-registered route not served: %q", w.Body.String())
+	}
+}
+
+func TestConflictKeepsTable(t *testing.T) {
+	api := NewAPIBuilder()
+	api.Get("/users/:id", write("user"))
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("conflicting route should panic")
+			}
+		}()
+		api.Get("/users/:name", write("conflict"))
+	}()
+	if w := serve(api, http.MethodGet, "/users/1"); w.Body.String() != "user" {
+		t.Fatalf("failed registration should not change the table: %q", w.Body.String())
+	}
+	if len(api.Routes()) != 1 {
+		t.Fatalf("failed registration should not be listed: %d", len(api.Routes()))
+	}
+}
+
+// 运行时注册和删除路由 与请求处理并发执行 配合-race检查
+func TestConcurrentRegistration(t *testing.T) {
+	api := NewAPIBuilder()
+	api.Get("/static", write("static"))
+	api.Get("/users/:id", write("user"))
+
+	var stop int32
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&stop) == 0 {
+				if w := serve(api, http.MethodGet, "/static"); w.Body.String() != "static" {
+					t.Errorf("static route lost: %q", w.Body.String())
+					return
+				}
+				if w := serve(api, http.MethodGet, "/users/1"); w.Body.String() != "user" {
+					t.Errorf("param route lost: %q", w.Body.String())
+					return
+				}
+				serve(api, http.MethodGet, "/feature/1/a")
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		pattern := "/feature/" + strconv.Itoa(i) + "/:name"
+		api.Get(pattern, write("feature"))
+		if i%10 == 0 {
+			api.Use(func(c *context.Context) { c.Next() })
+		}
+		if i%2 == 0 {
+			api.RemoveRoute(http.MethodGet, pattern)
+		}
+	}
+	atomic.StoreInt32(&stop, 1)
+	wg.Wait()
+
+	if w := serve(api, http.MethodGet, "/feature/1/a"); w.Body.String() != "feature" {
+		t.Fatalf("runtime route not served: %q", w.Body.String())
+	}
+	if w := serve(api, http.MethodGet, "/feature/2/a"); w.Code != http.StatusNotFound {
+		t.Fatalf("removed runtime route served: %d", w.Code)
+	}
+}
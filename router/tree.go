@@ -0,0 +1,337 @@
+package router
+
+import (
+	"fmt"
+	"github.com/yyxing/glu/context"
+	"strings"
+)
+
+type nodeType uint8
+
+const (
+	// 静态路径
+	static nodeType = iota
+	// :name 匹配一个路径段
+	param
+	// *name 匹配剩余的全部路径
+	catchAll
+)
+
+const (
+	paramPrefix    = ':'
+	catchAllPrefix = '*'
+)
+
+// 路由模式解析后的片段
+type segment struct {
+	typ nodeType
+	// 静态路径或参数名
+	text string
+	// 参数约束 没有约束时为nil
+	constraint *constraint
+}
+
+// Radix树节点 匹配优先级为 静态 > 参数 > 通配
+type node struct {
+	typ nodeType
+	// 静态节点为压缩后的公共前缀 参数节点为参数名
+	path string
+	// 静态子节点的首字母 与children一一对应
+	indices  string
+	children []*node
+	// 参数子节点 有约束的按注册顺序在前 无约束的在最后
+	// 同一位置相同约束只允许一个参数名
+	paramChildren []*node
+	// 参数约束
+	constraint *constraint
+	// 通配子节点
+	catchAllChild *node
+	// 以下字段仅在路由终点有值
+	pattern    string
+	paramNames []string
+	route      *Route
+	// 注册时生成的处理链和其中的分组中间件 路由表发布后不再修改
+	handlers    context.Handlers
+	middlewares context.Handlers
+}
+
+// 复制节点 子节点列表复制后可以独立修改
+func (n *node) clone() *node {
+	c := *n
+	c.children = append([]*node(nil), n.children...)
+	c.paramChildren = append([]*node(nil), n.paramChildren...)
+	return &c
+}
+
+// 解析路由模式 参数和通配符必须占据完整的路径段 通配符只能出现在最后
+// 参数可以带约束 例如 :id<int> :name<regex(^[a-z]+$)>
+// /users/:id/files/*filepath => [/users/] [:id] [/files/] [*filepath]
+func parsePattern(pattern string) ([]segment, error) {
+	if pattern == "" || pattern[0] != '/' {
+		return nil, fmt.Errorf("pattern '%s' must begin with '/'", pattern)
+	}
+	segments := make([]segment, 0)
+	names := make(map[string]bool)
+	start := 0
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+		if (c != paramPrefix && c != catchAllPrefix) || pattern[i-1] != '/' {
+			i++
+			continue
+		}
+		if start < i {
+			segments = append(segments, segment{typ: static, text: pattern[start:i]})
+		}
+		end := strings.IndexAny(pattern[i:], "/<")
+		if end < 0 {
+			end = len(pattern)
+		} else {
+			end += i
+		}
+		name := pattern[i+1 : end]
+		var cons *constraint
+		if end < len(pattern) && pattern[end] == '<' {
+			closing := constraintEnd(pattern[end+1:])
+			if closing < 0 {
+				return nil, fmt.Errorf("unclosed constraint in pattern '%s'", pattern)
+			}
+			expr := pattern[end+1 : end+1+closing]
+			end += closing + 2
+			if end < len(pattern) && pattern[end] != '/' {
+				return nil, fmt.Errorf("constraint must end the path segment in pattern '%s'", pattern)
+			}
+			if c == catchAllPrefix {
+				return nil, fmt.Errorf("catch-all '%s' can not have a constraint in pattern '%s'", name, pattern)
+			}
+			var err error
+			if cons, err = parseConstraint(expr); err != nil {
+				return nil, err
+			}
+		}
+		if name == "" {
+			return nil, fmt.Errorf("wildcard in pattern '%s' must have a name", pattern)
+		}
+		if strings.ContainsAny(name, ":*") {
+			return nil, fmt.Errorf("only one wildcard per path segment is allowed in pattern '%s'", pattern)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("duplicate param name '%s' in pattern '%s'", name, pattern)
+		}
+		names[name] = true
+		typ := param
+		if c == catchAllPrefix {
+			if end != len(pattern) {
+				return nil, fmt.Errorf("catch-all '%s' must be the last segment of pattern '%s'", name, pattern)
+			}
+			typ = catchAll
+		}
+		segments = append(segments, segment{typ: typ, text: name, constraint: cons})
+		start, i = end, end
+	}
+	if start < len(pattern) {
+		segments = append(segments, segment{typ: static, text: pattern[start:]})
+	}
+	return segments, nil
+}
+
+// 插入路由并返回路由终点 参数名冲突或重复注册时返回错误
+// 插入路径上的已有节点会被复制后替换 n需要是调用方复制过的节点 原有的树不会被修改
+func (n *node) insert(pattern string, route *Route) (*node, error) {
+	segments, err := parsePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	current := n
+	names := make([]string, 0)
+	for _, seg := range segments {
+		switch seg.typ {
+		case static:
+			current = current.insertStatic(seg.text)
+		case param:
+			child, err := current.insertParam(seg)
+			if err != nil {
+				return nil, fmt.Errorf("%v in pattern '%s'", err, pattern)
+			}
+			current = child
+			names = append(names, seg.text)
+		case catchAll:
+			if current.catchAllChild == nil {
+				current.catchAllChild = &node{typ: catchAll, path: seg.text}
+			} else if current.catchAllChild.path != seg.text {
+				return nil, fmt.Errorf("catch-all '*%s' in pattern '%s' conflicts with existing catch-all '*%s'",
+					seg.text, pattern, current.catchAllChild.path)
+			} else {
+				current.catchAllChild = current.catchAllChild.clone()
+			}
+			current = current.catchAllChild
+			names = append(names, seg.text)
+		}
+	}
+	if current.route != nil {
+		return nil, fmt.Errorf("pattern '%s' conflicts with existing route '%s'", pattern, current.pattern)
+	}
+	current.pattern = pattern
+	current.paramNames = names
+	current.route = route
+	current.handlers = route.Handlers
+	current.middlewares = route.Handlers[:route.Middlewares:route.Middlewares]
+	return current, nil
+}
+
+// 插入参数节点 相同约束下参数名不同视为冲突
+func (n *node) insertParam(seg segment) (*node, error) {
+	expr := seg.constraint.String()
+	for i, child := range n.paramChildren {
+		if child.constraint.String() != expr {
+			continue
+		}
+		if child.path != seg.text {
+			return nil, fmt.Errorf("param ':%s%s' conflicts with existing param ':%s%s'",
+				seg.text, expr, child.path, expr)
+		}
+		child = child.clone()
+		n.paramChildren[i] = child
+		return child, nil
+	}
+	child := &node{typ: param, path: seg.text, constraint: seg.constraint}
+	if seg.constraint == nil {
+		n.paramChildren = append(n.paramChildren, child)
+		return child, nil
+	}
+	// 有约束的参数放在无约束的参数之前
+	i := len(n.paramChildren)
+	if i > 0 && n.paramChildren[i-1].constraint == nil {
+		i--
+	}
+	n.paramChildren = append(n.paramChildren, nil)
+	copy(n.paramChildren[i+1:], n.paramChildren[i:])
+	n.paramChildren[i] = child
+	return child, nil
+}
+
+// 插入静态路径 必要时拆分已有节点 返回路径末尾对应的节点
+func (n *node) insertStatic(path string) *node {
+	current := n
+	for path != "" {
+		i := strings.IndexByte(current.indices, path[0])
+		if i < 0 {
+			child := &node{typ: static, path: path}
+			current.indices += path[:1]
+			current.children = append(current.children, child)
+			return child
+		}
+		child := current.children[i].clone()
+		current.children[i] = child
+		l := longestCommonPrefix(path, child.path)
+		if l < len(child.path) {
+			// 拆分子节点 公共前缀作为新的父节点
+			tail := *child
+			tail.path = child.path[l:]
+			*child = node{
+				typ:      static,
+				path:     child.path[:l],
+				indices:  tail.path[:1],
+				children: []*node{&tail},
+			}
+		}
+		current = child
+		path = path[l:]
+	}
+	return current
+}
+
+func longestCommonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// 查找路由 path为当前节点之后剩余的路径 参数值按顺序追加到values 参数名由调用方根据paramNames填充
+// 静态、参数、通配依次尝试 失败时回溯 values容量足够时不会分配内存
+func (n *node) search(path string, values *context.Params) *node {
+	if path == "" {
+		if n.route != nil {
+			return n
+		}
+		// 通配符可以匹配空路径
+		if child := n.catchAllChild; child != nil && child.route != nil {
+			*values = append(*values, context.Param{})
+			return child
+		}
+		return nil
+	}
+	if i := strings.IndexByte(n.indices, path[0]); i >= 0 {
+		child := n.children[i]
+		if len(path) >= len(child.path) && path[:len(child.path)] == child.path {
+			if found := child.search(path[len(child.path):], values); found != nil {
+				return found
+			}
+		}
+	}
+	if len(n.paramChildren) > 0 {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		// 参数不能为空
+		if end > 0 {
+			value := path[:end]
+			for _, child := range n.paramChildren {
+				if child.constraint != nil && !child.constraint.match(value) {
+					continue
+				}
+				*values = append(*values, context.Param{Value: value})
+				if found := child.search(path[end:], values); found != nil {
+					return found
+				}
+				*values = (*values)[:len(*values)-1]
+			}
+		}
+	}
+	if child := n.catchAllChild; child != nil && child.route != nil {
+		*values = append(*values, context.Param{Value: path})
+		return child
+	}
+	return nil
+}
+
+// 忽略大小写查找路由 fixed记录注册时的路径写法
+func (n *node) searchCaseInsensitive(path string, fixed []byte) ([]byte, bool) {
+	if path == "" {
+		if n.route != nil || (n.catchAllChild != nil && n.catchAllChild.route != nil) {
+			return fixed, true
+		}
+		return nil, false
+	}
+	// 大小写不同的静态子节点可能有多个
+	for _, child := range n.children {
+		if len(path) >= len(child.path) && strings.EqualFold(path[:len(child.path)], child.path) {
+			if out, ok := child.searchCaseInsensitive(path[len(child.path):], append(fixed, child.path...)); ok {
+				return out, true
+			}
+		}
+	}
+	if len(n.paramChildren) > 0 {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			value := path[:end]
+			for _, child := range n.paramChildren {
+				if child.constraint != nil && !child.constraint.match(value) {
+					continue
+				}
+				if out, ok := child.searchCaseInsensitive(path[end:], append(fixed, value...)); ok {
+					return out, true
+				}
+			}
+		}
+	}
+	if child := n.catchAllChild; child != nil && child.route != nil {
+		return append(fixed, path...), true
+	}
+	return nil, false
+}
@@ -0,0 +1,169 @@
+package router
+
+import (
+	"github.com/yyxing/glu/context"
+	"reflect"
+	"testing"
+)
+
+func newTestRouter() *Router {
+	r := NewRouter()
+	r.AddRouter("GET", "/", nil)
+	r.AddRouter("GET", "/hello/:name", nil)
+	r.AddRouter("GET", "/hello/b/c", nil)
+	r.AddRouter("GET", "/hi/:name", nil)
+	r.AddRouter("GET", "/assets/*filepath", nil)
+	return r
+}
+
+func TestParsePattern(t *testing.T) {
+	segments, err := parsePattern("/p/:name/*filepath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []segment{
+		{typ: static, text: "/p/"},
+		{typ: param, text: "name"},
+		{typ: static, text: "/"},
+		{typ: catchAll, text: "filepath"},
+	}
+	if !reflect.DeepEqual(segments, expected) {
+		t.Fatalf("test parsePattern failed: %v", segments)
+	}
+	// 段中间的:和*按普通字符处理
+	segments, err = parsePattern("/v1/a:b*c")
+	if err != nil || len(segments) != 1 || segments[0].text != "/v1/a:b*c" {
+		t.Fatalf("colon inside segment should be static: %v %v", segments, err)
+	}
+	for _, pattern := range []string{"p/:name", "/p/*", "/p/:", "/p/*name/*", "/p/*name/x", "/p/:a:b", "/p/:id/:id"} {
+		if _, err := parsePattern(pattern); err == nil {
+			t.Fatalf("pattern %s should be invalid", pattern)
+		}
+	}
+}
+
+func TestGetRoute(t *testing.T) {
+	r := newTestRouter()
+	n, ps := r.getRoute("GET", "/hello/geektutu")
+
+	if n == nil {
+		t.Fatal("nil shouldn't be returned")
+	}
+
+	if n.pattern != "/hello/:name" {
+		t.Fatal("should match /hello/:name")
+	}
+
+	if ps["name"] != "geektutu" {
+		t.Fatal("name should be equal to 'geektutu'")
+	}
+
+	t.Logf("matched path: %s, params['name']: %s", n.pattern, ps["name"])
+
+}
+
+func TestRoutePriority(t *testing.T) {
+	patterns := []string{
+		"/hello/:name",
+		"/hello/b/c",
+		"/hello/b",
+		"/hello/:name/c",
+		"/hello/*path",
+		"/files/*filepath",
+		"/files/new",
+		"/users/:id",
+		"/usersx",
+	}
+	tests := []struct {
+		path    string
+		pattern string
+		params  map[string]string
+	}{
+		{"/hello/b/c", "/hello/b/c", nil},
+		{"/hello/b", "/hello/b", nil},
+		{"/hello/bob", "/hello/:name", map[string]string{"name": "bob"}},
+		{"/hello/bob/c", "/hello/:name/c", map[string]string{"name": "bob"}},
+		// 静态节点匹配失败后回溯到参数节点
+		{"/hello/b/d", "/hello/*path", map[string]string{"path": "b/d"}},
+		{"/hello/x/y/z", "/hello/*path", map[string]string{"path": "x/y/z"}},
+		{"/files/new", "/files/new", nil},
+		{"/files/a/b.css", "/files/*filepath", map[string]string{"filepath": "a/b.css"}},
+		{"/files/", "/files/*filepath", map[string]string{"filepath": ""}},
+		{"/users/1", "/users/:id", map[string]string{"id": "1"}},
+		{"/usersx", "/usersx", nil},
+		{"/users/", "", nil},
+		{"/users/1/2", "", nil},
+		{"/hello", "", nil},
+	}
+	// 匹配结果与注册顺序无关
+	for _, order := range [][]string{patterns, reversed(patterns)} {
+		r := NewRouter()
+		for _, pattern := range order {
+			r.AddRouter("GET", pattern, nil)
+		}
+		for _, test := range tests {
+			n, params := r.getRoute("GET", test.path)
+			if test.pattern == "" {
+				if n != nil {
+					t.Fatalf("%s should not match, got %s", test.path, n.pattern)
+				}
+				continue
+			}
+			if n == nil || n.pattern != test.pattern {
+				t.Fatalf("%s should match %s, got %v", test.path, test.pattern, n)
+			}
+			if !reflect.DeepEqual(params, test.params) {
+				t.Fatalf("%s: unexpected params %v", test.path, params)
+			}
+		}
+	}
+}
+
+func reversed(s []string) []string {
+	r := make([]string, len(s))
+	for i, v := range s {
+		r[len(s)-1-i] = v
+	}
+	return r
+}
+
+func TestRouteConflict(t *testing.T) {
+	conflicts := [][2]string{
+		{"/users/:id", "/users/:name"},
+		{"/users/:id/posts", "/users/:name/comments"},
+		{"/files/*filepath", "/files/*path"},
+		{"/users/:id", "/users/:id"},
+	}
+	for _, conflict := range conflicts {
+		r := NewRouter()
+		r.AddRouter("GET", conflict[0], nil)
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s should conflict with %s", conflict[1], conflict[0])
+				}
+			}()
+			r.AddRouter("GET", conflict[1], nil)
+		}()
+	}
+	// 不同请求方法互不影响
+	r := NewRouter()
+	r.AddRouter("GET", "/users/:id", nil)
+	r.AddRouter("POST", "/users/:name", nil)
+}
+
+func TestSearchAllocs(t *testing.T) {
+	r := newTestRouter()
+	values := make(context.Params, 0, r.load().maxParams)
+	allocs := testing.AllocsPerRun(100, func() {
+		values = values[:0]
+		r.load().find("GET", "/hello/geektutu", &values)
+		values = values[:0]
+		r.load().find("GET", "/assets/css/main.css", &values)
+		values = values[:0]
+		r.load().find("GET", "/hello/b/c", &values)
+	})
+	if allocs != 0 {
+		t.Fatalf("search should not allocate, got %v allocs", allocs)
+	}
+}
@@ -0,0 +1,314 @@
+package glu
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// 阻塞运行服务 收到Shutdown后正常返回 其他错误直接退出
+func (e *Engine) Run(addr string) {
+	if err := e.RunContext(context.Background(), addr); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// 以HTTPS方式阻塞运行服务 TLS下自动协商HTTP/2 证书文件更新后无需重启即可生效
+func (e *Engine) RunTLS(addr string, certFile string, keyFile string) {
+	if err := e.RunTLSContext(context.Background(), addr, certFile, keyFile); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// 运行服务直到ctx结束 之后优雅关闭
+// 停止接收新连接 在ShutdownTimeout内等待处理中的请求完成 没有其他运行中的服务时执行OnShutdown注册的钩子
+func (e *Engine) RunContext(ctx context.Context, addr string) error {
+	return e.RunAddrs(ctx, addr)
+}
+
+// 在多个地址上同时运行同一个服务 例如对外端口和管理端口 直到ctx结束后优雅关闭
+func (e *Engine) RunAddrs(ctx context.Context, addrs ...string) error {
+	listeners := make([]net.Listener, 0, len(addrs))
+	for _, addr := range addrs {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			closeListeners(listeners)
+			return err
+		}
+		listeners = append(listeners, ln)
+	}
+	return e.Serve(ctx, listeners...)
+}
+
+// 在已创建的listener上阻塞运行服务 直到Shutdown
+func (e *Engine) RunListener(ln net.Listener) error {
+	return e.Serve(context.Background(), ln)
+}
+
+// 在unix socket上阻塞运行服务 直到Shutdown
+// 遗留的socket文件会被清理 socket仍有服务在监听时返回错误
+func (e *Engine) RunUnix(path string) error {
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("%s already exists and is not a socket", path)
+		}
+		conn, err := net.Dial("unix", path)
+		if err == nil {
+			_ = conn.Close()
+			return fmt.Errorf("listen unix %s: %w", path, syscall.EADDRINUSE)
+		}
+		// 只清理没有服务监听的socket文件
+		if !errors.Is(err, syscall.ECONNREFUSED) && !errors.Is(err, syscall.ENOENT) {
+			return err
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	return e.RunListener(ln)
+}
+
+// 在给定的listener上运行服务直到ctx结束 之后优雅关闭
+func (e *Engine) Serve(ctx context.Context, listeners ...net.Listener) error {
+	srv := e.newServer()
+	return e.serve(ctx, srv, listeners, "http", srv.Serve)
+}
+
+// 以HTTPS方式运行服务直到ctx结束 之后优雅关闭
+func (e *Engine) RunTLSContext(ctx context.Context, addr string, certFile string, keyFile string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return e.ServeTLS(ctx, certFile, keyFile, ln)
+}
+
+// 以HTTPS方式在给定的listener上运行服务直到ctx结束 之后优雅关闭
+func (e *Engine) ServeTLS(ctx context.Context, certFile string, keyFile string, listeners ...net.Listener) error {
+	reloader, err := newCertReloader(certFile, keyFile, e.CertReloadInterval)
+	if err != nil {
+		closeListeners(listeners)
+		return err
+	}
+	srv := e.newServer()
+	srv.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+	if err := http2.ConfigureServer(srv, nil); err != nil {
+		closeListeners(listeners)
+		return err
+	}
+	return e.serve(ctx, srv, listeners, "https", func(ln net.Listener) error {
+		// 证书由GetCertificate提供
+		return srv.ServeTLS(ln, "", "")
+	})
+}
+
+// 运行服务直到收到SIGINT或SIGTERM信号 之后优雅关闭
+func (e *Engine) RunGraceful(addr string) error {
+	ctx, stop := notifyContext()
+	defer stop()
+	return e.RunContext(ctx, addr)
+}
+
+// 以HTTPS方式运行服务直到收到SIGINT或SIGTERM信号 之后优雅关闭
+func (e *Engine) RunTLSGraceful(addr string, certFile string, keyFile string) error {
+	ctx, stop := notifyContext()
+	defer stop()
+	return e.RunTLSContext(ctx, addr, certFile, keyFile)
+}
+
+// 注册关闭钩子 在所有请求处理完成后按注册顺序执行 例如从注册中心注销、停止定时任务
+func (e *Engine) OnShutdown(hooks ...func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shutdownHooks = append(e.shutdownHooks, hooks...)
+}
+
+// 优雅关闭所有正在运行的服务 ctx用于控制等待请求处理完成的最长时间
+// 无论是否超时 关闭钩子都会执行且只执行一次
+func (e *Engine) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	servers := e.servers
+	e.servers = nil
+	e.listeners = nil
+	hooks := e.shutdownHooks
+	e.shutdownHooks = nil
+	e.mu.Unlock()
+
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+	)
+	for _, srv := range servers {
+		wg.Add(1)
+		go func(srv *http.Server) {
+			defer wg.Done()
+			if err := srv.Shutdown(ctx); err != nil {
+				errMu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				errMu.Unlock()
+			}
+		}(srv)
+	}
+	wg.Wait()
+	for _, hook := range hooks {
+		hook()
+	}
+	return firstErr
+}
+
+func (e *Engine) newServer() *http.Server {
+	var handler http.Handler = e
+	if e.EnableH2C {
+		handler = h2c.NewHandler(e, &http2.Server{IdleTimeout: e.server.idleTimeout})
+	}
+	srv := &http.Server{Handler: handler}
+	e.server.apply(srv)
+	return srv
+}
+
+// 返回当前正在监听的地址 监听":0"时可用于获取实际绑定的端口
+func (e *Engine) Addrs() []net.Addr {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	addrs := make([]net.Addr, 0, len(e.listeners))
+	for _, ln := range e.listeners {
+		addrs = append(addrs, ln.Addr())
+	}
+	return addrs
+}
+
+// 在所有listener上运行服务直到出错或ctx结束 Shutdown导致的退出不视为错误
+func (e *Engine) serve(ctx context.Context, srv *http.Server, listeners []net.Listener, scheme string,
+	serveFunc func(ln net.Listener) error) error {
+	// 记录服务 以便Shutdown时统一关闭
+	e.mu.Lock()
+	e.servers = append(e.servers, srv)
+	e.listeners = append(e.listeners, listeners...)
+	e.mu.Unlock()
+
+	if e.Debug {
+		e.printRoutes(os.Stdout)
+	}
+	errCh := make(chan error, len(listeners))
+	for _, ln := range listeners {
+		log.Printf("Now listening on: %s://%s\n", scheme, ln.Addr())
+		go func(ln net.Listener) {
+			if err := serveFunc(ln); err != nil && err != http.ErrServerClosed {
+				errCh <- err
+				return
+			}
+			errCh <- nil
+		}(ln)
+	}
+	for remaining := len(listeners); remaining > 0; remaining-- {
+		select {
+		case err := <-errCh:
+			if err != nil {
+				// 任一listener出错则关闭整个服务
+				e.untrack(srv, listeners)
+				_ = srv.Close()
+				return err
+			}
+		case <-ctx.Done():
+			// 只关闭本次启动的服务 其他Serve启动的服务不受影响
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), e.ShutdownTimeout)
+			defer cancel()
+			e.untrack(srv, listeners)
+			err := srv.Shutdown(shutdownCtx)
+			// 最后一个服务关闭后执行关闭钩子
+			if e.running() == 0 {
+				if shutdownErr := e.Shutdown(shutdownCtx); err == nil {
+					err = shutdownErr
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// 正在运行的服务数
+func (e *Engine) running() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.servers)
+}
+
+func (e *Engine) untrack(srv *http.Server, listeners []net.Listener) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, s := range e.servers {
+		if s == srv {
+			e.servers = append(e.servers[:i:i], e.servers[i+1:]...)
+			break
+		}
+	}
+	remain := e.listeners[:0:0]
+	for _, ln := range e.listeners {
+		owned := false
+		for _, l := range listeners {
+			if ln == l {
+				owned = true
+				break
+			}
+		}
+		if !owned {
+			remain = append(remain, ln)
+		}
+	}
+	e.listeners = remain
+}
+
+// 打印路由表
+func (e *Engine) printRoutes(w io.Writer) {
+	for _, route := range e.Routes() {
+		_, _ = fmt.Fprintf(w, "[GLU-debug] %-7s %-30s --> %s (%d handlers)\n",
+			route.Method, route.Host+route.Path, route.Handler, len(route.Handlers))
+	}
+}
+
+func closeListeners(listeners []net.Listener) {
+	for _, ln := range listeners {
+		_ = ln.Close()
+	}
+}
+
+// 返回一个在收到SIGINT或SIGTERM信号时结束的ctx
+func notifyContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-quit:
+			log.Printf("Received signal %s, shutting down...\n", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(quit)
+		cancel()
+	}
+}
@@ -0,0 +1,101 @@
+package glu
+
+import (
+	"crypto/tls"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// 默认的证书文件检查间隔
+	defaultCertReloadInterval = time.Minute
+)
+
+// 证书热加载 握手时按间隔检查证书文件的修改时间 有变化则重新加载
+// 重新加载失败时继续使用旧证书
+type certReloader struct {
+	certFile  string
+	keyFile   string
+	interval  time.Duration
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	lastCheck time.Time
+}
+
+func newCertReloader(certFile string, keyFile string, interval time.Duration) (*certReloader, error) {
+	if interval <= 0 {
+		interval = defaultCertReloadInterval
+	}
+	reloader := &certReloader{certFile: certFile, keyFile: keyFile, interval: interval}
+	if err := reloader.reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+func (r *certReloader) reload() error {
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.certMod = certMod
+	r.keyMod = keyMod
+	r.lastCheck = time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *certReloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+// 检查证书文件是否有更新
+func (r *certReloader) maybeReload() {
+	r.mu.Lock()
+	if time.Since(r.lastCheck) < r.interval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastCheck = time.Now()
+	certMod, keyMod := r.certMod, r.keyMod
+	r.mu.Unlock()
+
+	newCertMod, newKeyMod, err := r.modTimes()
+	if err != nil {
+		log.Printf("check certificate error: %v\n", err)
+		return
+	}
+	if newCertMod.Equal(certMod) && newKeyMod.Equal(keyMod) {
+		return
+	}
+	if err := r.reload(); err != nil {
+		log.Printf("reload certificate error: %v\n", err)
+		return
+	}
+	log.Printf("certificate %s reloaded\n", r.certFile)
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.maybeReload()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
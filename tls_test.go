@@ -0,0 +1,168 @@
+package glu
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	gluContext "github.com/yyxing/glu/context"
+	"golang.org/x/net/http2"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestCert(t *testing.T, dir string, commonName string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	if err := ioutil.WriteFile(certFile, certPem, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, keyPem, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func commonName(t *testing.T, r *certReloader) string {
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestCertReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "glu-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeTestCert(t, dir, "first")
+	reloader, err := newCertReloader(certFile, keyFile, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := commonName(t, reloader); name != "first" {
+		t.Fatalf("expected first certificate, got %s", name)
+	}
+	writeTestCert(t, dir, "second")
+	// 保证修改时间发生变化
+	future := time.Now().Add(time.Minute)
+	_ = os.Chtimes(certFile, future, future)
+	_ = os.Chtimes(keyFile, future, future)
+	time.Sleep(5 * time.Millisecond)
+	if name := commonName(t, reloader); name != "second" {
+		t.Fatalf("expected reloaded certificate, got %s", name)
+	}
+	// 文件损坏时继续使用旧证书
+	if err := ioutil.WriteFile(certFile, []byte("broken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := future.Add(time.Minute)
+	_ = os.Chtimes(certFile, later, later)
+	time.Sleep(5 * time.Millisecond)
+	if name := commonName(t, reloader); name != "second" {
+		t.Fatalf("expected previous certificate to be kept, got %s", name)
+	}
+}
+
+// 在临时端口上运行服务 返回请求使用的HTTP主版本号
+func serveProto(t *testing.T, engine *Engine, run func(ctx context.Context, ln net.Listener) error, client *http.Client, scheme string) int {
+	proto := make(chan int, 1)
+	engine.Get("/proto", func(c *gluContext.Context) {
+		proto <- c.Request.ProtoMajor
+	})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- run(ctx, ln)
+	}()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}()
+	resp, err := client.Get(scheme + "://" + ln.Addr().String() + "/proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	client.CloseIdleConnections()
+	return <-proto
+}
+
+func TestServeTLSHTTP2(t *testing.T) {
+	dir, err := ioutil.TempDir("", "glu-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeTestCert(t, dir, "localhost")
+	engine := New()
+	client := &http.Client{Transport: &http2.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	proto := serveProto(t, engine, func(ctx context.Context, ln net.Listener) error {
+		return engine.ServeTLS(ctx, certFile, keyFile, ln)
+	}, client, "https")
+	if proto != 2 {
+		t.Fatalf("expected HTTP/2 over TLS, got HTTP/%d", proto)
+	}
+}
+
+func TestServeH2C(t *testing.T) {
+	engine := New(WithH2C())
+	// 不经过TLS直接使用HTTP/2
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network string, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	proto := serveProto(t, engine, func(ctx context.Context, ln net.Listener) error {
+		return engine.Serve(ctx, ln)
+	}, client, "http")
+	if proto != 2 {
+		t.Fatalf("expected h2c, got HTTP/%d", proto)
+	}
+}